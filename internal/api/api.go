@@ -0,0 +1,251 @@
+// Package api implements ccq's local HTTP/JSON API, for tools that read the
+// queue or control switching (menubar widgets, editor panels, launchers).
+//
+//	GET  /api/session                   session settings and all windows
+//	GET  /api/windows                   all windows
+//	POST /api/windows/{window}/focus    select a window
+//	POST /api/windows/{window}/snooze   {"duration": "20m"} or {"duration": "off"}
+//	POST /api/auto-switch               {"enabled": true|false}; empty body toggles
+//	GET  /api/events                    Server-Sent Events stream of state changes
+//
+// {window} is a window ID (@3) or index (3). Sessions and windows use the
+// status package's schema. Errors are returned as {"error": "..."} with a
+// 4xx/5xx status.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// DefaultEventInterval is how often the event stream polls for state changes,
+// matching the status bar refresh.
+const DefaultEventInterval = time.Second
+
+// Event is one entry of the event stream. Type is "added", "changed" or
+// "removed" for windows, or "session" when session settings change.
+type Event struct {
+	Type          string          `json:"type"`
+	Window        *status.Window  `json:"window,omitempty"`
+	PreviousState string          `json:"previous_state,omitempty"`
+	Session       *status.Session `json:"session,omitempty"`
+}
+
+// Server serves the API for one ccq session.
+type Server struct {
+	tm  tmux.Backend
+	q   *queue.Queue
+	sw  *switcher.Switcher
+	mux *http.ServeMux
+
+	// EventInterval is how often /api/events polls for state changes.
+	EventInterval time.Duration
+}
+
+// New creates a Server for the given tmux backend.
+func New(tm tmux.Backend) *Server {
+	q := queue.New(tm)
+	s := &Server{
+		tm:            tm,
+		q:             q,
+		sw:            switcher.New(tm, q),
+		mux:           http.NewServeMux(),
+		EventInterval: DefaultEventInterval,
+	}
+	s.mux.HandleFunc("GET /api/session", s.handleSession)
+	s.mux.HandleFunc("GET /api/windows", s.handleWindows)
+	s.mux.HandleFunc("POST /api/windows/{window}/focus", s.handleFocus)
+	s.mux.HandleFunc("POST /api/windows/{window}/snooze", s.handleSnooze)
+	s.mux.HandleFunc("POST /api/auto-switch", s.handleAutoSwitch)
+	s.mux.HandleFunc("GET /api/events", s.handleEvents)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// LocalOnly guards a handler served on a loopback TCP address against
+// browsers: requests carrying an Origin header (cross-site requests from web
+// pages) are refused, as are Host headers other than loopback ones (DNS
+// rebinding).
+func LocalOnly(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("browser requests are not allowed"))
+			return
+		}
+		if !IsLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not a loopback address", r.Host))
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// IsLoopbackHost reports whether host (optionally with a port) is localhost
+// or a loopback IP.
+func IsLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// window finds the window referenced by the {window} path segment.
+func (s *Server) window(r *http.Request) (status.Window, error) {
+	ref := r.PathValue("window")
+	sess, err := status.Load(s.tm)
+	if err != nil {
+		return status.Window{}, err
+	}
+	for _, w := range sess.Windows {
+		if w.ID == ref || w.Index == ref {
+			return w, nil
+		}
+	}
+	return status.Window{}, errNotFound{ref}
+}
+
+type errNotFound struct{ ref string }
+
+func (e errNotFound) Error() string {
+	return fmt.Sprintf("window %q not found", e.ref)
+}
+
+func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
+	sess, err := status.Load(s.tm)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, sess)
+}
+
+func (s *Server) handleWindows(w http.ResponseWriter, r *http.Request) {
+	sess, err := status.Load(s.tm)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, sess.Windows)
+}
+
+func (s *Server) handleFocus(w http.ResponseWriter, r *http.Request) {
+	win, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	if err := s.sw.Focus(win.ID, switcher.ReasonAPI); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.respondWindow(w, r)
+}
+
+func (s *Server) handleSnooze(w http.ResponseWriter, r *http.Request) {
+	win, ok := s.lookup(w, r)
+	if !ok {
+		return
+	}
+	var body struct {
+		Duration string `json:"duration"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+
+	var err error
+	if body.Duration == "off" {
+		err = s.q.Unsnooze(win.ID)
+	} else {
+		d, perr := time.ParseDuration(body.Duration)
+		if perr != nil || d <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid duration %q (e.g. 20m, 1h30m, off)", body.Duration))
+			return
+		}
+		if err = s.q.Snooze(win.ID, d); err == nil {
+			s.sw.RetryAfter(d)
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.respondWindow(w, r)
+}
+
+func (s *Server) handleAutoSwitch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Enabled *bool `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+
+	enabled := !s.sw.IsAutoSwitchOn()
+	if body.Enabled != nil {
+		enabled = *body.Enabled
+	}
+	if err := s.sw.SetAutoSwitch(enabled); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if enabled {
+		// Check queue immediately when enabling, like prefix + a
+		s.sw.TrySwitch()
+	}
+	writeJSON(w, map[string]bool{"enabled": enabled})
+}
+
+// lookup resolves the {window} path segment, writing an error response if it
+// cannot.
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) (status.Window, bool) {
+	win, err := s.window(r)
+	var nf errNotFound
+	switch {
+	case errors.As(err, &nf):
+		writeError(w, http.StatusNotFound, err)
+		return status.Window{}, false
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err)
+		return status.Window{}, false
+	}
+	return win, true
+}
+
+// respondWindow writes the window's state after a change.
+func (s *Server) respondWindow(w http.ResponseWriter, r *http.Request) {
+	if win, ok := s.lookup(w, r); ok {
+		writeJSON(w, win)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,207 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/api"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// setup starts the API on a fake session with two windows; w0 is active.
+func setup(t *testing.T) (*tmux.Fake, *httptest.Server, string, string) {
+	t.Helper()
+	f := tmux.NewFake("ccq-fake-api")
+	windows, _ := f.ListWindows()
+	w1, _ := f.NewWindow("/src/api")
+	srv := api.New(f)
+	srv.EventInterval = 10 * time.Millisecond
+	ts := httptest.NewServer(srv)
+	t.Cleanup(ts.Close)
+	return f, ts, windows[0].ID, w1
+}
+
+func do(t *testing.T, method, url, body string, out any) int {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, strings.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("decode %s %s: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestWindows(t *testing.T) {
+	t.Parallel()
+	f, ts, w0, w1 := setup(t)
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+	q.SetSubstate(w1, queue.SubstateWaitingPermission)
+
+	var windows []status.Window
+	if code := do(t, "GET", ts.URL+"/api/windows", "", &windows); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(windows))
+	}
+	if w := windows[0]; w.ID != w0 || !w.Active || w.State != "busy" {
+		t.Errorf("unexpected w0: %+v", w)
+	}
+	if w := windows[1]; w.ID != w1 || w.State != "idle" || w.Substate != queue.SubstateWaitingPermission || w.IdleSince == 0 || w.Priority != queue.PriorityNormal {
+		t.Errorf("unexpected w1: %+v", w)
+	}
+
+	var sess status.Session
+	do(t, "GET", ts.URL+"/api/session", "", &sess)
+	if sess.Name != "ccq-fake-api" || sess.Policy != queue.DefaultPolicy || len(sess.Windows) != 2 {
+		t.Errorf("unexpected session: %+v", sess)
+	}
+}
+
+func TestFocusAndSnooze(t *testing.T) {
+	t.Parallel()
+	f, ts, _, w1 := setup(t)
+
+	var win status.Window
+	if code := do(t, "POST", ts.URL+"/api/windows/1/focus", "", &win); code != http.StatusOK {
+		t.Fatalf("focus: status %d", code)
+	}
+	if active, _ := f.ActiveWindowID(); active != w1 || !win.Active {
+		t.Errorf("expected %s to be focused, got %s (%+v)", w1, active, win)
+	}
+
+	do(t, "POST", ts.URL+"/api/windows/"+w1+"/snooze", `{"duration": "20m"}`, &win)
+	if win.SnoozedUntil < time.Now().Add(19*time.Minute).Unix() {
+		t.Errorf("expected window to be snoozed ~20m, got %d", win.SnoozedUntil)
+	}
+	var unsnoozed status.Window
+	do(t, "POST", ts.URL+"/api/windows/"+w1+"/snooze", `{"duration": "off"}`, &unsnoozed)
+	if unsnoozed.SnoozedUntil != 0 {
+		t.Errorf("expected snooze to be lifted, got %d", unsnoozed.SnoozedUntil)
+	}
+
+	var e map[string]string
+	if code := do(t, "POST", ts.URL+"/api/windows/"+w1+"/snooze", `{"duration": "soon"}`, &e); code != http.StatusBadRequest || e["error"] == "" {
+		t.Errorf("expected 400 with error, got %d %v", code, e)
+	}
+	if code := do(t, "POST", ts.URL+"/api/windows/@99/focus", "", &e); code != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown window, got %d", code)
+	}
+}
+
+func TestAutoSwitch(t *testing.T) {
+	t.Parallel()
+	f, ts, w0, w1 := setup(t)
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+	sw := switcher.New(f, q)
+
+	var resp map[string]bool
+	do(t, "POST", ts.URL+"/api/auto-switch", "", &resp)
+	if !resp["enabled"] || !sw.IsAutoSwitchOn() {
+		t.Fatalf("expected empty body to toggle auto-switch on, got %v", resp)
+	}
+	// Enabling checks the queue immediately
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected switch to %s, got %s", w1, active)
+	}
+
+	do(t, "POST", ts.URL+"/api/auto-switch", `{"enabled": false}`, &resp)
+	if resp["enabled"] || sw.IsAutoSwitchOn() {
+		t.Errorf("expected auto-switch off, got %v", resp)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	f, ts, _, w1 := setup(t)
+
+	resp, err := http.Get(ts.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() (string, string) {
+		var name, data string
+		for lines.Scan() {
+			line := lines.Text()
+			if line == "" {
+				return name, data
+			}
+			if v, ok := strings.CutPrefix(line, "event: "); ok {
+				name = v
+			} else if v, ok := strings.CutPrefix(line, "data: "); ok {
+				data = v
+			}
+		}
+		t.Fatalf("stream ended: %v", lines.Err())
+		return "", ""
+	}
+
+	if name, _ := next(); name != "snapshot" {
+		t.Fatalf("expected snapshot first, got %q", name)
+	}
+
+	queue.New(f).MarkIdle(w1)
+	name, data := next()
+	var ev api.Event
+	json.Unmarshal([]byte(data), &ev)
+	if name != "changed" || ev.Window == nil || ev.Window.ID != w1 || ev.Window.State != "idle" || ev.PreviousState != "" {
+		t.Errorf("unexpected event %s: %s", name, data)
+	}
+
+	f.KillWindow(w1)
+	if name, _ := next(); name != "removed" {
+		t.Errorf("expected removed event, got %q", name)
+	}
+}
+
+func TestLocalOnly(t *testing.T) {
+	t.Parallel()
+	h := api.LocalOnly(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		host, origin string
+		want         int
+	}{
+		{"127.0.0.1:7767", "", http.StatusOK},
+		{"localhost:7767", "", http.StatusOK},
+		{"[::1]:7767", "", http.StatusOK},
+		{"127.0.0.1:7767", "https://example.com", http.StatusForbidden},
+		{"evil.example.com:7767", "", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/api/windows", nil)
+		req.Host = c.host
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != c.want {
+			t.Errorf("host %q origin %q: got %d, want %d", c.host, c.origin, rec.Code, c.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jingikim/ccq/internal/status"
+)
+
+// handleEvents streams state changes as Server-Sent Events. The stream opens
+// with a "snapshot" event carrying the whole session, then polls every
+// EventInterval and sends one event per change (see diff).
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming not supported"))
+		return
+	}
+
+	prev, err := status.Load(s.tm)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	writeEvent(w, "snapshot", prev)
+	flusher.Flush()
+
+	ticker := time.NewTicker(s.EventInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur, err := status.Load(s.tm)
+		if err != nil {
+			continue
+		}
+		events := diff(prev, cur)
+		for _, ev := range events {
+			writeEvent(w, ev.Type, ev)
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+		}
+		prev = cur
+	}
+}
+
+// writeEvent writes one SSE event with a JSON payload.
+func writeEvent(w http.ResponseWriter, name string, v any) {
+	data, _ := json.Marshal(v)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
+
+// diff returns the events that turn prev into cur: "session" when auto-switch,
+// policy, the pending switch or the attached clients changed, then per window "added", "changed"
+// (state, sub-state, focus, priority, snooze or exclusion) or "removed".
+func diff(prev, cur status.Session) []Event {
+	var events []Event
+	if prev.AutoSwitch != cur.AutoSwitch || prev.Policy != cur.Policy || prev.PendingSwitch != cur.PendingSwitch ||
+		len(prev.Clients) != len(cur.Clients) {
+		sess := cur
+		sess.Windows = nil
+		events = append(events, Event{Type: "session", Session: &sess})
+	}
+
+	before := make(map[string]status.Window, len(prev.Windows))
+	for _, w := range prev.Windows {
+		before[w.ID] = w
+	}
+	for _, w := range cur.Windows {
+		old, ok := before[w.ID]
+		delete(before, w.ID)
+		switch {
+		case !ok:
+			events = append(events, Event{Type: "added", Window: &w})
+		case windowChanged(old, w):
+			events = append(events, Event{Type: "changed", Window: &w, PreviousState: old.State})
+		}
+	}
+	for _, w := range prev.Windows {
+		if _, ok := before[w.ID]; ok {
+			events = append(events, Event{Type: "removed", Window: &w, PreviousState: w.State})
+		}
+	}
+	return events
+}
+
+// windowChanged reports whether a window changed in a way clients care about.
+// Notification and tool details follow state changes, so they are not compared.
+func windowChanged(a, b status.Window) bool {
+	return a.State != b.State || a.Substate != b.Substate || a.Active != b.Active ||
+		a.Priority != b.Priority || a.SnoozedUntil != b.SnoozedUntil || a.Excluded != b.Excluded
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"os/signal"
+	"reflect"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jingikim/ccq/internal/daemon"
+	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// daemonTick is how often the daemon refreshes its snapshot and checks timers.
+const daemonTick = time.Second
+
+// Daemon runs the optional ccq daemon for the session in the foreground until
+// the session ends or the process is signalled.
+func Daemon() error {
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+
+	srv, err := daemon.Listen(sessionName)
+	if err != nil {
+		return err
+	}
+	d := newDaemonState(tm)
+	d.refresh()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		ticker := time.NewTicker(daemonTick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sigs:
+				srv.Close()
+				return
+			case <-ticker.C:
+				if !tm.HasSession() {
+					srv.Close()
+					return
+				}
+				d.tick()
+			}
+		}
+	}()
+
+	return srv.Serve(d.serve)
+}
+
+// daemonState is the daemon's view of the session. Requests and snapshot
+// refreshes are serialized by mu, so the daemon is the single writer for the
+// hooks it receives; switches retried by a tick are serialized with them by
+// the session lock instead.
+type daemonState struct {
+	mu sync.Mutex
+	tm tmux.Backend
+	q  *queue.Queue
+	sw *switcher.Switcher
+	h  *hook.Handler
+
+	// windows is the snapshot the status line is rendered from, refreshed
+	// after every hook and on every tick.
+	windows []queue.Window
+
+	// version counts snapshot changes; changed is closed and replaced on each
+	// change to wake CmdWait requests.
+	version uint64
+	changed chan struct{}
+
+	// gitBusy is set while refreshGit reads git statuses outside mu.
+	gitBusy bool
+}
+
+func newDaemonState(tm tmux.Backend) *daemonState {
+	q := queue.New(tm)
+	sw := switcher.New(tm, q)
+	sw.ReconcileOnTick()
+	return &daemonState{tm: tm, q: q, sw: sw, h: hook.New(tm, q, sw), changed: make(chan struct{})}
+}
+
+// refresh re-reads the session snapshot. Callers hold mu. Due git statuses
+// are read in the background, since a slow `git status` must not hold up
+// hooks.
+func (d *daemonState) refresh() error {
+	windows, err := d.q.Snapshot(status.WindowKeys...)
+	if err != nil {
+		return err
+	}
+	if !d.gitBusy && status.GitDue(windows) {
+		d.gitBusy = true
+		go d.refreshGit(cloneWindows(windows))
+	}
+	d.update(windows)
+	return nil
+}
+
+// refreshGit runs status.RefreshGit on a copy of a snapshot without holding
+// mu, then merges the statuses it read into the current snapshot.
+func (d *daemonState) refreshGit(windows []queue.Window) {
+	status.RefreshGit(d.tm, windows)
+	read := make(map[string]string, len(windows))
+	for _, w := range windows {
+		read[w.ID] = w.Option[status.GitKey]
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.gitBusy = false
+	merged := cloneWindows(d.windows)
+	for _, w := range merged {
+		if v, ok := read[w.ID]; ok {
+			w.Option[status.GitKey] = v
+		}
+	}
+	d.update(merged)
+}
+
+// update stores a new snapshot, waking CmdWait requests if it changed.
+// Callers hold mu.
+func (d *daemonState) update(windows []queue.Window) {
+	if !reflect.DeepEqual(windows, d.windows) {
+		d.version++
+		close(d.changed)
+		d.changed = make(chan struct{})
+	}
+	d.windows = windows
+}
+
+// cloneWindows copies a snapshot, so it can be changed while the original is
+// in use.
+func cloneWindows(windows []queue.Window) []queue.Window {
+	out := make([]queue.Window, len(windows))
+	for i, w := range windows {
+		w.Option = maps.Clone(w.Option)
+		out[i] = w
+	}
+	return out
+}
+
+// wait serves CmdWait without holding mu while blocked.
+func (d *daemonState) wait(req daemon.Request) (string, error) {
+	timeout := req.Timeout
+	if timeout <= 0 || timeout > daemon.MaxWait {
+		timeout = daemon.MaxWait
+	}
+
+	d.mu.Lock()
+	version, changed := d.version, d.changed
+	d.mu.Unlock()
+
+	if version == req.Version {
+		select {
+		case <-changed:
+		case <-time.After(timeout):
+		}
+		d.mu.Lock()
+		version = d.version
+		d.mu.Unlock()
+	}
+	return strconv.FormatUint(version, 10), nil
+}
+
+// serve handles one request from a ccq client.
+func (d *daemonState) serve(req daemon.Request) (string, error) {
+	if req.Cmd == daemon.CmdWait {
+		return d.wait(req)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	switch req.Cmd {
+	case daemon.CmdHook:
+		windowID, err := d.tm.WindowIDFromPane(req.Pane)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve window from pane %s: %w", req.Pane, err)
+		}
+		err = handleHook(d.h, req.Action, windowID, req.Payload)
+		d.refresh()
+		return "", err
+	case daemon.CmdStatus:
+		if d.windows == nil {
+			if err := d.refresh(); err != nil {
+				return "", err
+			}
+		}
+		return formatStatusLine(status.Windows(d.windows)), nil
+	case daemon.CmdSessionStatus:
+		// From the snapshot: loading the session could run git under mu
+		if d.windows == nil {
+			if err := d.refresh(); err != nil {
+				return "", err
+			}
+		}
+		return formatSessionStatus(status.FromSnapshot(d.tm, d.windows), tableOptions{}), nil
+	default:
+		return "", fmt.Errorf("unknown daemon command: %s", req.Cmd)
+	}
+}
+
+// tick refreshes the snapshot and fires the timers no hook reports: a window
+// whose snooze ran out rejoins the queue, and a pending switch is retried.
+// Switching waits for the session lock, which a CLI command may hold, so it
+// runs without mu.
+func (d *daemonState) tick() {
+	d.mu.Lock()
+	prev := d.windows
+	err := d.refresh()
+	cur := d.windows
+	d.mu.Unlock()
+	if err != nil {
+		return
+	}
+
+	var switched bool
+	if snoozeExpired(prev, cur) {
+		switched = d.sw.Retry()
+	} else {
+		switched = d.sw.Reconcile()
+	}
+	if switched {
+		d.mu.Lock()
+		d.refresh()
+		d.mu.Unlock()
+	}
+}
+
+// snoozeExpired reports whether a window snoozed in prev is idle and no
+// longer snoozed in cur.
+func snoozeExpired(prev, cur []queue.Window) bool {
+	snoozed := make(map[string]bool)
+	for _, w := range prev {
+		if w.SnoozedUntil > 0 {
+			snoozed[w.ID] = true
+		}
+	}
+	for _, w := range cur {
+		if snoozed[w.ID] && w.SnoozedUntil == 0 && w.IsIdle() {
+			return true
+		}
+	}
+	return false
+}
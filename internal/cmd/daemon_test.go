@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/daemon"
+	"github.com/jingikim/ccq/internal/lock"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestDaemonServe_HookUpdatesCachedStatus(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-daemon")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+	f.SetPanePath(w0, "/src/web")
+
+	d := newDaemonState(f)
+	switcher.New(f, d.q).SetAutoSwitch(true)
+	f.SetWindowOption(w1, queue.StateKey, "idle")
+	f.SetWindowOption(w1, queue.IdleSinceKey, fmt.Sprint(time.Now().Add(-3*time.Minute).Unix()))
+
+	if _, err := d.serve(daemon.Request{Cmd: daemon.CmdHook, Action: "prompt", Pane: f.PaneID(w0)}); err != nil {
+		t.Fatalf("hook: %v", err)
+	}
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected switch to %s, got %s", w1, active)
+	}
+
+	// The status line is served from the snapshot taken after the hook
+	before := f.Calls("ListWindows")
+	line, err := d.serve(daemon.Request{Cmd: daemon.CmdStatus})
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if want := "● 0:web | ▶ 1:api    0/2 idle"; line != want {
+		t.Errorf("status =\n  %q\nwant\n  %q", line, want)
+	}
+	if n := f.Calls("ListWindows") - before; n != 0 {
+		t.Errorf("expected status from the cached snapshot, got %d list-windows calls", n)
+	}
+
+	if _, err := d.serve(daemon.Request{Cmd: daemon.CmdHook, Action: "bogus", Pane: f.PaneID(w0)}); err == nil {
+		t.Error("expected error for unknown hook action")
+	}
+	if _, err := d.serve(daemon.Request{Cmd: "bogus"}); err == nil {
+		t.Error("expected error for unknown command")
+	}
+}
+
+func TestDaemonTick_SnoozeExpirySwitches(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-daemon-snooze")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	d := newDaemonState(f)
+	switcher.New(f, d.q).SetAutoSwitch(true)
+	d.q.MarkBusy(w0)
+	d.q.MarkIdle(w1)
+	d.q.Snooze(w1, time.Hour)
+	d.tick()
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Fatalf("expected no switch while snoozed, got %s", active)
+	}
+
+	// The snooze runs out without any hook firing
+	f.SetWindowOption(w1, queue.SnoozedUntilKey, fmt.Sprint(time.Now().Add(-time.Second).Unix()))
+	d.tick()
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected switch to %s after the snooze ran out, got %s", w1, active)
+	}
+}
+
+func TestDaemonWait_WakesOnChange(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-daemon-wait")
+	windows, _ := f.ListWindows()
+	// No background git refresh to bump the version
+	f.SetSessionOption(status.GitStatusKey, "off")
+	d := newDaemonState(f)
+	d.refresh()
+	version := d.version
+
+	// Unchanged session: times out at the requested interval
+	start := time.Now()
+	out, _ := d.serve(daemon.Request{Cmd: daemon.CmdWait, Version: version, Timeout: 50 * time.Millisecond})
+	if out != fmt.Sprint(version) || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("expected timeout with version %d, got %q after %v", version, out, time.Since(start))
+	}
+
+	done := make(chan string)
+	go func() {
+		out, _ := d.serve(daemon.Request{Cmd: daemon.CmdWait, Version: version, Timeout: daemon.MaxWait})
+		done <- out
+	}()
+	time.Sleep(20 * time.Millisecond)
+	d.q.MarkIdle(windows[0].ID)
+	d.tick()
+
+	select {
+	case out := <-done:
+		if out != fmt.Sprint(version+1) {
+			t.Errorf("expected version %d, got %q", version+1, out)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait did not wake on change")
+	}
+}
+
+func TestDaemonRefresh_ReadsGitInBackground(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "-C", repo, "init", "-q", "-b", "main").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	f := tmux.NewFake("ccq-fake-daemon-git")
+	windows, _ := f.ListWindows()
+	f.SetPanePath(windows[0].ID, repo)
+
+	d := newDaemonState(f)
+	d.mu.Lock()
+	d.refresh()
+	version := d.version
+	// The git status is merged in once read, not before refresh returns
+	if d.windows[0].Option[status.GitKey] != "" {
+		t.Error("expected the git status to be read in the background")
+	}
+	d.mu.Unlock()
+
+	d.serve(daemon.Request{Cmd: daemon.CmdWait, Version: version, Timeout: 5 * time.Second})
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if git := status.Windows(d.windows)[0].Git; git == nil || git.Branch != "main" {
+		t.Errorf("expected branch main merged into the snapshot, got %+v", git)
+	}
+	if d.gitBusy {
+		t.Error("expected the background refresh to be done")
+	}
+}
+
+func TestDaemonServe_SessionStatusFromSnapshot(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-daemon-session")
+	f.SetSessionOption(status.GitStatusKey, "off")
+	d := newDaemonState(f)
+	d.refresh()
+
+	before := f.Calls("ListWindows")
+	out, err := d.serve(daemon.Request{Cmd: daemon.CmdSessionStatus})
+	if err != nil {
+		t.Fatalf("session status: %v", err)
+	}
+	if !strings.Contains(out, "1 window") {
+		t.Errorf("expected the session table, got:\n%s", out)
+	}
+	if n := f.Calls("ListWindows") - before; n != 0 {
+		t.Errorf("expected the table from the cached snapshot, got %d list-windows calls", n)
+	}
+}
+
+func TestDaemonTick_SwitchesWithoutMu(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-daemon-tick-lock")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+	f.SetSessionOption(status.GitStatusKey, "off")
+
+	d := newDaemonState(f)
+	switcher.New(f, d.q).SetAutoSwitch(true)
+	d.q.MarkBusy(w0)
+	d.q.MarkIdle(w1)
+	f.SetSessionOption(switcher.PendingSwitchKey, switcher.PendingLocked)
+
+	// A CLI command holds the session lock while the tick retries the switch
+	l, err := lock.Acquire(f.SessionName(), lock.DefaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		d.tick()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	served := make(chan struct{})
+	go func() {
+		d.serve(daemon.Request{Cmd: daemon.CmdStatus})
+		close(served)
+	}()
+	select {
+	case <-served:
+	case <-time.After(500 * time.Millisecond):
+		t.Error("expected status served while the tick waits for the lock")
+	}
+	l.Release()
+	<-done
+	<-served
+
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected the pending switch to %s retried, got %s", w1, active)
+	}
+}
+
+func TestDaemon_DeferredSwitchNotScheduled(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-daemon-defer")
+	d := newDaemonState(f)
+	d.sw.DeferSwitch(switcher.PendingLocked, switcher.LockRetryDelay)
+	if got := d.sw.PendingSwitch(); got != switcher.PendingLocked {
+		t.Errorf("expected the pending switch recorded, got %q", got)
+	}
+	if shell := f.Shell(); len(shell) != 0 {
+		t.Errorf("expected the next tick to retry instead of ccq _reconcile, got %q", shell)
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// Exclude removes a window from auto-switching. Its idle/busy state is still
+// tracked so the dashboard stays accurate.
+// Usage: ccq exclude [window]
+func Exclude(args []string) error {
+	return setExcluded(args, true)
+}
+
+// Include returns an excluded window to auto-switching.
+// Usage: ccq include [window]
+func Include(args []string) error {
+	return setExcluded(args, false)
+}
+
+func setExcluded(args []string, excluded bool) error {
+	if len(args) > 1 {
+		if excluded {
+			return fmt.Errorf("usage: ccq exclude [window]")
+		}
+		return fmt.Errorf("usage: ccq include [window]")
+	}
+	var ref string
+	if len(args) == 1 {
+		ref = args[0]
+	}
+
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+
+	windowID, err := resolveWindow(tm, ref)
+	if err != nil {
+		return err
+	}
+
+	q := queue.New(tm)
+	return q.SetExcluded(windowID, excluded)
+}
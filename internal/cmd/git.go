@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// RefreshGit reads the due git statuses of all windows into their caches.
+// Scheduled by status.ScheduleGit via `run-shell -b`, so renders never wait
+// on git.
+func RefreshGit() error {
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return nil
+	}
+	defer tm.UnsetSessionOption(status.GitPendingKey)
+	windows, err := queue.New(tm).Snapshot(status.WindowKeys...)
+	if err != nil {
+		return err
+	}
+	status.RefreshGit(tm, windows)
+	return nil
+}
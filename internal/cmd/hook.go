@@ -4,18 +4,47 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/jingikim/ccq/internal/daemon"
 	"github.com/jingikim/ccq/internal/hook"
 	"github.com/jingikim/ccq/internal/queue"
 	"github.com/jingikim/ccq/internal/switcher"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
+// readHookPayload parses the payload Claude Code pipes to hooks on stdin.
+// Returns an empty payload when stdin is a terminal (manual invocation) or
+// cannot be parsed, so state tracking never depends on it.
+func readHookPayload() hook.Payload {
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice != 0 {
+		return hook.Payload{}
+	}
+	p, err := hook.ParsePayload(os.Stdin)
+	if err != nil {
+		return hook.Payload{}
+	}
+	return p
+}
+
+// Hook handles a Claude Code hook for the window of $TMUX_PANE. The event is
+// passed to the daemon when one is running, otherwise handled here.
 func Hook(action string) error {
 	pane := os.Getenv("TMUX_PANE")
 	if pane == "" {
 		return fmt.Errorf("TMUX_PANE not set (not running inside tmux?)")
 	}
 
+	payload := readHookPayload()
+
+	_, err := daemon.Call(sessionName, daemon.Request{
+		Cmd:     daemon.CmdHook,
+		Action:  action,
+		Pane:    pane,
+		Payload: payload,
+	})
+	if err != daemon.ErrNotRunning {
+		return err
+	}
+
 	tm := tmux.New(sessionName)
 	if !tm.HasSession() {
 		return nil
@@ -28,17 +57,20 @@ func Hook(action string) error {
 
 	q := queue.New(tm)
 	sw := switcher.New(tm, q)
-	h := hook.New(tm, q, sw)
+	return handleHook(hook.New(tm, q, sw), action, windowID, payload)
+}
 
+// handleHook dispatches a hook action to the handler.
+func handleHook(h *hook.Handler, action, windowID string, payload hook.Payload) error {
 	switch action {
 	case "idle":
-		return h.HandleIdle(windowID)
+		return h.HandleIdle(windowID, payload)
 	case "busy":
-		return h.HandleBusy(windowID)
+		return h.HandleBusy(windowID, payload)
 	case "prompt":
-		return h.HandlePromptSubmit(windowID)
+		return h.HandlePromptSubmit(windowID, payload)
 	case "remove":
-		return h.HandleRemove(windowID)
+		return h.HandleRemove(windowID, payload)
 	default:
 		return fmt.Errorf("unknown hook action: %s", action)
 	}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jingikim/ccq/internal/config"
+	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// defaultCommand is what new windows run when the config sets no command.
+const defaultCommand = "claude"
+
+// launchSpec describes a window running a profile's command in dir: the
+// command template rendered for dir, then the profile's args and extra (the
+// arguments given after `ccq --`), shell-quoted. {{.Dir}} is quoted too, since
+// dir comes from the command line, the new-window prompt or a task file.
+func launchSpec(p config.Profile, dir string, extra []string) (tmux.WindowSpec, error) {
+	command := p.Command
+	if command == "" {
+		command = defaultCommand
+	}
+	tmpl, err := template.New("command").Option("missingkey=error").Parse(command)
+	if err != nil {
+		return tmux.WindowSpec{}, fmt.Errorf("invalid command in config: %w", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, struct{ Dir string }{shellQuote(dir)}); err != nil {
+		return tmux.WindowSpec{}, fmt.Errorf("invalid command in config: %w", err)
+	}
+
+	for _, arg := range append(append([]string(nil), p.Args...), extra...) {
+		b.WriteString(" " + shellQuote(arg))
+	}
+	return tmux.WindowSpec{Dir: dir, Command: b.String(), Env: p.Env}, nil
+}
+
+// shellQuote quotes s for sh unless it consists only of safe characters.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=+,./:@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// setupWindow records how a window was launched on it: its profile (see
+// applyProfile), worktree, priority and initial prompt.
+func setupWindow(tm tmux.Backend, windowID string, opts launchOptions, p config.Profile) error {
+	if err := applyProfile(tm, windowID, opts.profile, p); err != nil {
+		return err
+	}
+	if opts.worktree != "" {
+		tm.SetWindowOption(windowID, status.WorktreeKey, opts.worktree)
+		tm.SetWindowOption(windowID, status.WorktreeBranchKey, opts.branch)
+	}
+	if opts.priority != "" {
+		if err := queue.New(tm).SetPriority(windowID, opts.priority); err != nil {
+			return err
+		}
+	}
+	if opts.prompt != "" {
+		return tm.SetWindowOption(windowID, hook.InitialPromptKey, opts.prompt)
+	}
+	return nil
+}
+
+// applyProfile records the profile a window was launched with and applies its
+// queue defaults.
+func applyProfile(tm tmux.Backend, windowID, name string, p config.Profile) error {
+	if name != "" {
+		tm.SetWindowOption(windowID, status.ProfileKey, name)
+	}
+	q := queue.New(tm)
+	if p.Priority != "" {
+		if err := q.SetPriority(windowID, p.Priority); err != nil {
+			return fmt.Errorf("profile %q: %w", name, err)
+		}
+	}
+	if p.Exclude {
+		return q.SetExcluded(windowID, true)
+	}
+	return nil
+}
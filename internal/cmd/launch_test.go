@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/config"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestLaunchSpec(t *testing.T) {
+	tests := []struct {
+		name  string
+		p     config.Profile
+		extra []string
+		want  string
+	}{
+		{"default", config.Profile{}, nil, "claude"},
+		{"passthrough", config.Profile{}, []string{"--model", "opus", "--continue"}, "claude --model opus --continue"},
+		{
+			"config args first, quoted",
+			config.Profile{Args: []string{"--permission-mode", "plan"}},
+			[]string{"--append-system-prompt", "don't push"},
+			`claude --permission-mode plan --append-system-prompt 'don'\''t push'`,
+		},
+		{"template", config.Profile{Command: "direnv exec {{.Dir}} claude"}, nil, "direnv exec /src/api claude"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := launchSpec(tt.p, "/src/api", tt.extra)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if spec.Command != tt.want {
+				t.Errorf("command = %q, want %q", spec.Command, tt.want)
+			}
+			if spec.Dir != "/src/api" {
+				t.Errorf("dir = %q", spec.Dir)
+			}
+		})
+	}
+}
+
+func TestLaunchSpec_QuotesDir(t *testing.T) {
+	p := config.Profile{Command: "direnv exec {{.Dir}} claude"}
+	dir := "/home/u/my proj; rm -rf $(pwd)"
+	spec, err := launchSpec(p, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `direnv exec '/home/u/my proj; rm -rf $(pwd)' claude`
+	if spec.Command != want {
+		t.Errorf("command = %q, want %q", spec.Command, want)
+	}
+	if spec.Dir != dir {
+		t.Errorf("dir = %q", spec.Dir)
+	}
+}
+
+func TestLaunchSpec_Env(t *testing.T) {
+	p := config.Profile{Env: map[string]string{"ANTHROPIC_MODEL": "opus"}}
+	spec, _ := launchSpec(p, "/src", nil)
+	if !reflect.DeepEqual(spec.Env, p.Env) {
+		t.Errorf("env = %v, want %v", spec.Env, p.Env)
+	}
+}
+
+func TestLaunchSpec_InvalidTemplate(t *testing.T) {
+	for _, command := range []string{"claude {{.Dir", "claude {{.Nope}}"} {
+		if _, err := launchSpec(config.Profile{Command: command}, "/src", nil); err == nil {
+			t.Errorf("expected an error for %q", command)
+		}
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-profile")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/docs")
+
+	if err := applyProfile(f, w0, "", config.Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyProfile(f, w1, "review", config.Profile{Priority: "high", Exclude: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	q := queue.New(f)
+	if v, _ := f.GetWindowOption(w0, status.ProfileKey); v != "" || q.Priority(w0) != queue.PriorityNormal || q.IsExcluded(w0) {
+		t.Errorf("expected no profile defaults on %s", w0)
+	}
+	if v, _ := f.GetWindowOption(w1, status.ProfileKey); v != "review" {
+		t.Errorf("expected profile recorded, got %q", v)
+	}
+	if q.Priority(w1) != queue.PriorityHigh || !q.IsExcluded(w1) {
+		t.Errorf("expected high priority and excluded, got %s, %v", q.Priority(w1), q.IsExcluded(w1))
+	}
+
+	if err := applyProfile(f, w1, "bad", config.Profile{Priority: "urgent"}); err == nil {
+		t.Error("expected an invalid priority to be reported")
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// Next selects the window auto-switch would serve next, even in manual mode.
+// Usage: ccq next [--client tty]
+func Next(args []string) error {
+	return navigate(args, "next", "no window waiting", (*switcher.Switcher).Next)
+}
+
+// Prev returns to the window left with the latest ccq next.
+// Usage: ccq prev [--client tty]
+func Prev(args []string) error {
+	return navigate(args, "prev", "no earlier window", (*switcher.Switcher).Prev)
+}
+
+// Back returns to the window ccq last switched away from, walking further back
+// through the switch history on each call.
+// Usage: ccq back [--client tty]
+func Back(args []string) error {
+	return navigate(args, "back", "no earlier switch", func(sw *switcher.Switcher, _ string) (string, error) {
+		return sw.Back()
+	})
+}
+
+// navigate runs a Switcher navigation for the client given with --client
+// (the key bindings pass #{client_tty}) or the session's current client.
+// When there is nowhere to go, the client is told so in its status line.
+func navigate(args []string, name, none string, move func(*switcher.Switcher, string) (string, error)) error {
+	var client string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--client" && i+1 < len(args):
+			i++
+			client = args[i]
+		case strings.HasPrefix(args[i], "--client="):
+			client = strings.TrimPrefix(args[i], "--client=")
+		default:
+			return fmt.Errorf("usage: ccq %s [--client tty]", name)
+		}
+	}
+
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+	if client == "" {
+		client = tm.CurrentClient()
+	}
+
+	sw := switcher.New(tm, queue.New(tm))
+	target, err := move(sw, client)
+	if err != nil {
+		return err
+	}
+	if target == "" {
+		if client == "" {
+			fmt.Println("ccq: " + none)
+			return nil
+		}
+		return tm.DisplayMessage(client, "ccq: "+none)
+	}
+	return nil
+}
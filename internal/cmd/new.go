@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// recentDirsKey is the session option listing the directories windows were
+// last opened in, most recent first, colon-separated.
+const recentDirsKey = "@ccq_recent_dirs"
+
+// newDirKey is the session option the new-window prompt stores its input in,
+// so run-shell can pass it to ccq new with #{q:}, quoted for the shell.
+const newDirKey = "@ccq_new_dir"
+
+// maxRecentDirs bounds the recent directories offered by the new-window menu,
+// one per digit key.
+const maxRecentDirs = 9
+
+// New opens a window in the given directory (default: the current one),
+// optionally with a name, a named config profile, in a git worktree of the
+// directory's repository, or in the background, starting the session if
+// needed. Arguments after "--" are passed on to the launched command.
+// Usage: ccq new [dir] [--name N] [--profile name] [--worktree branch] [--background] [-- args...]
+func New(args []string) error {
+	usage := fmt.Errorf("usage: ccq new [dir] [--name N] [--profile name] [--worktree branch] [--background] [-- args...]")
+	var opts launchOptions
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			opts.args = args[i+1:]
+			i = len(args)
+		case args[i] == "--name" && i+1 < len(args):
+			i++
+			opts.name = args[i]
+		case strings.HasPrefix(args[i], "--name="):
+			opts.name = strings.TrimPrefix(args[i], "--name=")
+		case args[i] == "--profile" && i+1 < len(args):
+			i++
+			opts.profile = args[i]
+		case strings.HasPrefix(args[i], "--profile="):
+			opts.profile = strings.TrimPrefix(args[i], "--profile=")
+		case args[i] == "--worktree" && i+1 < len(args):
+			i++
+			opts.branch = args[i]
+		case strings.HasPrefix(args[i], "--worktree="):
+			opts.branch = strings.TrimPrefix(args[i], "--worktree=")
+		case args[i] == "--background" || args[i] == "-b":
+			opts.background = true
+		case strings.HasPrefix(args[i], "-") || opts.dir != "":
+			return usage
+		default:
+			opts.dir = args[i]
+		}
+	}
+
+	dir, err := launchDir(opts.dir)
+	if err != nil {
+		return err
+	}
+	opts.dir = dir
+	return launch(opts)
+}
+
+// launchDir resolves the directory given to ccq new to an absolute path,
+// defaulting to the current directory. A leading ~ is expanded, since
+// directories typed at the tmux prompt do not go through a shell.
+func launchDir(dir string) (string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	abs, err := filepath.Abs(expandHome(dir))
+	if err != nil {
+		return "", err
+	}
+	if fi, err := os.Stat(abs); err != nil || !fi.IsDir() {
+		return "", fmt.Errorf("not a directory: %s", dir)
+	}
+	return abs, nil
+}
+
+// expandHome replaces a leading ~ in path with the home directory.
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~"); ok && (rest == "" || rest[0] == '/') {
+		if home := homeDir(); home != "" {
+			return home + rest
+		}
+	}
+	return path
+}
+
+// recentDirs returns the directories windows were last opened in, most
+// recent first.
+func recentDirs(tm tmux.Backend) []string {
+	val, _ := tm.GetSessionOption(recentDirsKey)
+	if val == "" {
+		return nil
+	}
+	return strings.Split(val, ":")
+}
+
+// recordRecentDir moves dir to the front of the recent directories.
+// Directories containing the separator are not recorded.
+func recordRecentDir(tm tmux.Backend, dir string) {
+	if strings.Contains(dir, ":") {
+		return
+	}
+	dirs := []string{dir}
+	for _, d := range recentDirs(tm) {
+		if d != dir && len(dirs) < maxRecentDirs {
+			dirs = append(dirs, d)
+		}
+	}
+	tm.SetSessionOption(recentDirsKey, strings.Join(dirs, ":"))
+}
+
+// NewMenu shows the new-window menu bound to prefix + O on the given client:
+// the recent directories on digit keys, and a prompt for any other directory.
+// Usage: ccq _new_menu [tty]
+func NewMenu(args []string) error {
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+
+	menu := []string{"display-menu", "-T", "#[align=centre]ccq new"}
+	if len(args) > 0 && args[0] != "" {
+		menu = append(menu, "-c", args[0])
+	}
+	menu = append(menu, newMenuItems(recentDirs(tm), homeDir())...)
+	_, err := tm.Run(menu...)
+	return err
+}
+
+// newMenuItems returns display-menu items (name, key, command) opening a
+// window in each recent directory, then one prompting for a directory. The
+// prompt keeps its own history, so earlier entries are a keypress away. Its
+// input reaches ccq new without the shell interpreting it.
+func newMenuItems(dirs []string, home string) []string {
+	var items []string
+	for i, dir := range dirs {
+		name := dir
+		if home != "" {
+			if rest, ok := strings.CutPrefix(dir, home); ok && (rest == "" || rest[0] == '/') {
+				name = "~" + rest
+			}
+		}
+		command := "run-shell " + tmuxQuote("ccq new "+shellQuote(dir))
+		items = append(items, tmuxEscape(name), strconv.Itoa(i+1), command)
+	}
+	if len(items) > 0 {
+		items = append(items, "")
+	}
+	// %%% escapes the input for the double quotes; ## survives the menu's own
+	// format expansion
+	prompt := fmt.Sprintf(`set-option %[1]s \"%%%%%%\" ; run-shell \"ccq new ##{q:%[1]s}\"`, newDirKey)
+	return append(items, "Other directory…", "o", `command-prompt -p "new window in:" "`+prompt+`"`)
+}
+
+// tmuxQuote quotes s as a single argument in a tmux command string.
+func tmuxQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "#", "##")
+	return `"` + r.Replace(s) + `"`
+}
+
+// tmuxEscape escapes format characters in text shown by tmux.
+func tmuxEscape(s string) string {
+	return strings.ReplaceAll(s, "#", "##")
+}
+
+func homeDir() string {
+	home, _ := os.UserHomeDir()
+	return home
+}
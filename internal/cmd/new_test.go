@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestRecordRecentDir(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-recent")
+	for _, dir := range []string{"/src/a", "/src/b", "/src/a", "/src/c:d"} {
+		recordRecentDir(f, dir)
+	}
+	if got, want := recentDirs(f), []string{"/src/a", "/src/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("recentDirs = %q, want %q", got, want)
+	}
+
+	for i := 0; i < 2*maxRecentDirs; i++ {
+		recordRecentDir(f, filepath.Join("/src", string(rune('a'+i))))
+	}
+	if n := len(recentDirs(f)); n != maxRecentDirs {
+		t.Errorf("expected %d recent dirs, got %d", maxRecentDirs, n)
+	}
+}
+
+func TestNewMenuItems(t *testing.T) {
+	items := newMenuItems([]string{"/home/me/src/api", "/srv/#tmp"}, "/home/me")
+	want := []string{
+		"~/src/api", "1", `run-shell "ccq new /home/me/src/api"`,
+		"/srv/##tmp", "2", `run-shell "ccq new '/srv/##tmp'"`,
+		"",
+		"Other directory…", "o",
+		`command-prompt -p "new window in:" "set-option @ccq_new_dir \"%%%\" ; run-shell \"ccq new ##{q:@ccq_new_dir}\""`,
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("newMenuItems =\n  %q\nwant\n  %q", items, want)
+	}
+
+	if items := newMenuItems(nil, ""); len(items) != 3 {
+		t.Errorf("expected only the prompt item without recent dirs, got %q", items)
+	}
+}
+
+func TestLaunchDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	os.Mkdir(filepath.Join(home, "src"), 0755)
+
+	if got, err := launchDir("~/src"); err != nil || got != filepath.Join(home, "src") {
+		t.Errorf("launchDir(~/src) = %q, %v", got, err)
+	}
+	if _, err := launchDir(filepath.Join(home, "missing")); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+	wd, _ := os.Getwd()
+	if got, _ := launchDir(""); got != wd {
+		t.Errorf("expected the current directory by default, got %q", got)
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// Policy shows or changes the session's scheduling policy.
+// Usage: ccq policy [fifo|lifo|round-robin|weighted]
+func Policy(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: ccq policy [%s]", strings.Join(queue.PolicyNames(), "|"))
+	}
+
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+	q := queue.New(tm)
+
+	if len(args) == 0 {
+		fmt.Printf("policy: %s (available: %s)\n", q.Policy().Name(), strings.Join(queue.PolicyNames(), ", "))
+		return nil
+	}
+
+	if err := q.SetPolicy(args[0]); err != nil {
+		return err
+	}
+	// A new policy may pick a different window; re-evaluate right away
+	// like Toggle does when enabling auto-switch.
+	sw := switcher.New(tm, q)
+	sw.TrySwitch()
+	return nil
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// Priority sets the queue priority of a window.
+// Usage: ccq priority [window] high|normal|low
+func Priority(args []string) error {
+	var ref, level string
+	switch len(args) {
+	case 1:
+		level = args[0]
+	case 2:
+		ref, level = args[0], args[1]
+	default:
+		return fmt.Errorf("usage: ccq priority [window] high|normal|low")
+	}
+
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+
+	windowID, err := resolveWindow(tm, ref)
+	if err != nil {
+		return err
+	}
+
+	q := queue.New(tm)
+	return q.SetPriority(windowID, level)
+}
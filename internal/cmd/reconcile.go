@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// Reconcile re-evaluates a deferred auto-switch. With --retry the switch rules
+// are applied even if no switch is pending, e.g. when a snooze runs out.
+// Scheduled by the switcher via `run-shell -b`.
+// Usage: ccq _reconcile [--retry]
+func Reconcile(args []string) error {
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return nil
+	}
+	q := queue.New(tm)
+	sw := switcher.New(tm, q)
+	if len(args) > 0 && args[0] == "--retry" {
+		sw.Retry()
+	} else {
+		sw.Reconcile()
+	}
+	return nil
+}
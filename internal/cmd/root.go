@@ -5,30 +5,57 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"time"
 
 	"github.com/jingikim/ccq/internal/config"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/switcher"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
 const (
 	sessionName   = "ccq"
-	configVersion = "3" // Increment when session settings change (keybindings, status bar, etc.)
+	configVersion = "11" // Increment when session settings change (keybindings, status bar, etc.)
 )
 
 // initSessionSettings applies all settings for a newly created session.
-func initSessionSettings(tm *tmux.Tmux, prefix string) error {
+func initSessionSettings(tm *tmux.Tmux, cfg *config.Config) error {
 	tm.SetSessionOption("@ccq_auto_switch", "on")
 	tm.SetSessionOption("remain-on-exit", "off")
-	if err := tm.SetSessionOption("prefix", prefix); err != nil {
-		return fmt.Errorf("failed to set prefix key %q: %w", prefix, err)
+	if err := tm.SetSessionOption("prefix", cfg.Prefix); err != nil {
+		return fmt.Errorf("failed to set prefix key %q: %w", cfg.Prefix, err)
+	}
+	q := queue.New(tm)
+	if cfg.Policy != "" {
+		if err := q.SetPolicy(cfg.Policy); err != nil {
+			return fmt.Errorf("invalid policy in config: %w", err)
+		}
+	}
+	q.SetPrioritizePermission(cfg.PrioritizePermission)
+	if cfg.GitStatus != nil && !*cfg.GitStatus {
+		tm.SetSessionOption(status.GitStatusKey, "off")
+	}
+	if cfg.TypingGrace != "" {
+		d, err := time.ParseDuration(cfg.TypingGrace)
+		if err != nil {
+			return fmt.Errorf("invalid typing_grace in config: %w", err)
+		}
+		if err := switcher.New(tm, q).SetTypingGrace(d); err != nil {
+			return fmt.Errorf("invalid typing_grace in config: %w", err)
+		}
 	}
 	applyVersionedSettings(tm)
 	tm.SetSessionOption("@ccq_config_version", configVersion)
+	if cfg.Daemon {
+		tm.RunShell("ccq daemon")
+	}
 	return nil
 }
 
 // migrateSessionSettings updates only versioned settings without touching user preferences.
-// Preserves: prefix, @ccq_auto_switch, remain-on-exit
+// Preserves: prefix, @ccq_auto_switch, @ccq_policy, @ccq_prioritize_permission,
+// @ccq_typing_grace, @ccq_git_status, remain-on-exit
 func migrateSessionSettings(tm *tmux.Tmux) {
 	applyVersionedSettings(tm)
 	tm.SetSessionOption("@ccq_config_version", configVersion)
@@ -41,8 +68,9 @@ func applyVersionedSettings(tm *tmux.Tmux) {
 	tm.SetSessionOption("status-left", "[#{?#{==:#{@ccq_auto_switch},on},AUTO,MANUAL}] ")
 	tm.SetSessionOption("status-right", "#{session_windows} windows")
 	tm.SetSessionOption("status-style", "bg=colour236,fg=colour248")
-	tm.SetSessionOption("window-status-current-format", "#[fg=colour214,bold]#I:#{b:pane_current_path}#{?#{@ccq_state}, #{@ccq_state},}")
-	tm.SetSessionOption("window-status-format", "#I:#{b:pane_current_path}#{?#{@ccq_state}, #{@ccq_state},}")
+	// Windows show their directory unless named explicitly (ccq new --name)
+	tm.SetSessionOption("window-status-current-format", "#[fg=colour214,bold]#I:#{?automatic-rename,#{b:pane_current_path},#W}#{?#{@ccq_state}, #{@ccq_state},}")
+	tm.SetSessionOption("window-status-format", "#I:#{?automatic-rename,#{b:pane_current_path},#W}#{?#{@ccq_state}, #{@ccq_state},}")
 
 	// Dashboard status bar (line 1 - top)
 	tm.SetSessionOption("status", "2")
@@ -52,25 +80,85 @@ func applyVersionedSettings(tm *tmux.Tmux) {
 	// Keybindings
 	tm.Run("bind-key", "-T", "prefix", "a", "run-shell", "ccq _toggle")
 	tm.Run("bind-key", "-T", "prefix", "g", "run-shell", "ccq toggle-dashboard")
+	tm.Run("bind-key", "-T", "prefix", "P", "command-prompt", "-p", "priority (high/normal/low):",
+		"run-shell \"ccq priority #{window_id} '%%'\"")
+	tm.Run("bind-key", "-T", "prefix", "S", "command-prompt", "-I", "20m", "-p", "snooze for (or off):",
+		"run-shell \"ccq snooze #{window_id} '%%'\"")
+	tm.Run("bind-key", "-T", "prefix", "X", "if-shell", "-F", "#{==:#{@ccq_excluded},on}",
+		"run-shell 'ccq include #{window_id}'", "run-shell 'ccq exclude #{window_id}'")
+	tm.Run("bind-key", "-T", "prefix", "N", "run-shell", "ccq next --client '#{client_tty}'")
+	tm.Run("bind-key", "-T", "prefix", "B", "run-shell", "ccq prev --client '#{client_tty}'")
+	tm.Run("bind-key", "-T", "prefix", "BSpace", "run-shell", "ccq back --client '#{client_tty}'")
+	tm.Run("bind-key", "-T", "prefix", "O", "run-shell", "-b", "ccq _new_menu '#{client_tty}'")
+	tm.Run("bind-key", "-T", "prefix", "u", "display-popup", "-E", "-w", "90%", "-h", "80%", "ccq ui")
+
+	// Retry a pending auto-switch when the user changes window or attaches
+	reconcile := "if-shell -F '#{@ccq_pending_switch}' \"run-shell -b 'ccq _reconcile'\""
+	for _, hook := range []string{"session-window-changed", "client-session-changed", "client-attached"} {
+		tm.Run("set-hook", "-t", tm.Session, hook, reconcile)
+	}
 }
 
-func Root() error {
+// Root starts the ccq session in the current directory, or adds a window to
+// it if it is running. args (given after `ccq --`) are passed on to the
+// launched command.
+func Root(args []string) error {
+	dir, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return launch(launchOptions{dir: dir, args: args})
+}
+
+// launchOptions describes the window Root and New open.
+type launchOptions struct {
+	dir        string
+	name       string   // window name; "" names it after the command
+	profile    string   // config profile; "" for the top-level launch settings
+	branch     string   // run in a git worktree of dir with this branch
+	priority   string   // queue priority, overriding the profile's
+	prompt     string   // submitted once Claude Code first goes idle
+	args       []string // passed on to the command
+	background bool     // open without focusing or attaching
+
+	worktree string // set by launch when branch is given
+}
+
+// launch opens a window as described by opts, starting the session first if
+// it is not running.
+func launch(opts launchOptions) error {
 	if !tmux.IsInstalled() {
 		return fmt.Errorf("tmux is not installed. Install it with: brew install tmux")
 	}
 
 	tm := tmux.New(sessionName)
 
-	// Session exists — add a new window
-	if tm.HasSession() {
-		return addWindow(tm)
-	}
-
 	// Load config
 	cfg, err := config.Load(config.DefaultPath())
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	profile, err := cfg.Profile(opts.profile)
+	if err != nil {
+		return err
+	}
+	dir := opts.dir
+	if opts.branch != "" {
+		if dir, err = openWorktree(cfg.WorktreeRoot, opts.dir, opts.branch); err != nil {
+			return err
+		}
+		opts.worktree = dir
+	}
+	spec, err := launchSpec(profile, dir, opts.args)
+	if err != nil {
+		return err
+	}
+	spec.Name = opts.name
+
+	// Session exists — add a new window
+	if tm.HasSession() {
+		return addWindow(tm, opts, spec, profile)
+	}
 
 	// First run: configure prefix key
 	if cfg.Prefix == "" {
@@ -80,23 +168,28 @@ func Root() error {
 		}
 	}
 
-	// Create tmux session
-	if err := tm.NewSession(); err != nil {
+	// Create tmux session, running the command in its first window
+	if err := tm.NewSessionWith(spec); err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
 
 	// Apply all session settings
-	if err := initSessionSettings(tm, cfg.Prefix); err != nil {
+	if err := initSessionSettings(tm, cfg); err != nil {
 		tm.KillSession()
 		return err
 	}
-
-	// Start claude in the first window
-	windows, _ := tm.ListWindows()
-	if len(windows) > 0 {
-		tm.SendKeys(windows[0].ID, "claude", true)
+	if windows, _ := tm.ListWindows(); len(windows) > 0 {
+		if err := setupWindow(tm, windows[0].ID, opts, profile); err != nil {
+			tm.KillSession()
+			return err
+		}
 	}
+	recordRecentDir(tm, opts.dir)
 
+	if opts.background {
+		fmt.Printf("✓ started ccq session with %s\n", spec.Dir)
+		return nil
+	}
 	return attachOrSwitch(tm)
 }
 
@@ -116,7 +209,7 @@ func runInteractive(name string, args ...string) error {
 	return cmd.Run()
 }
 
-func addWindow(tm *tmux.Tmux) error {
+func addWindow(tm *tmux.Tmux, opts launchOptions, spec tmux.WindowSpec, profile config.Profile) error {
 	// Check if session configuration needs migration
 	currentVersion, _ := tm.GetSessionOption("@ccq_config_version")
 	if currentVersion != configVersion {
@@ -124,20 +217,23 @@ func addWindow(tm *tmux.Tmux) error {
 		fmt.Printf("✓ ccq settings updated (v%s → v%s)\n", currentVersion, configVersion)
 	}
 
-	dir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get working directory: %w", err)
-	}
-
 	activeID, _ := tm.ActiveWindowID()
 
-	windowID, err := tm.NewWindow(dir)
+	windowID, err := tm.StartWindow(spec)
 	if err != nil {
-		return fmt.Errorf("failed to create window: %w", err)
+		return fmt.Errorf("failed to start %s: %w", spec.Command, err)
+	}
+	if err := setupWindow(tm, windowID, opts, profile); err != nil {
+		tm.KillWindow(windowID)
+		return err
 	}
+	recordRecentDir(tm, opts.dir)
 
-	if err := tm.SendKeys(windowID, "claude", true); err != nil {
-		return fmt.Errorf("failed to start claude: %w", err)
+	// In the background the window waits in the queue like any other once
+	// its setup finishes, so there is nowhere to return to
+	if opts.background {
+		fmt.Printf("✓ opened %s in the background\n", spec.Dir)
+		return nil
 	}
 
 	// Mark return target so HandleIdle can switch back after initial setup
@@ -155,7 +251,7 @@ func addWindow(tm *tmux.Tmux) error {
 	}
 	// No clients attached — don't set @ccq_return_to, stay attached after init
 
-	tm.SelectWindow(windowID)
+	switcher.New(tm, queue.New(tm)).Focus(windowID, switcher.ReasonNew)
 
 	if !inTmux {
 		return runInteractive("tmux", "attach-session", "-t", sessionName)
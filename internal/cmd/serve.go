@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jingikim/ccq/internal/api"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// defaultListen is the address `ccq serve` listens on without --listen.
+const defaultListen = "127.0.0.1:7767"
+
+// Serve runs the local HTTP/JSON API until the session ends or the process is
+// signalled.
+// Usage: ccq serve [--listen host:port|unix:/path]
+func Serve(args []string) error {
+	addr := defaultListen
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--listen" && i+1 < len(args):
+			i++
+			addr = args[i]
+		case strings.HasPrefix(args[i], "--listen="):
+			addr = strings.TrimPrefix(args[i], "--listen=")
+		default:
+			return fmt.Errorf("usage: ccq serve [--listen host:port|unix:/path]")
+		}
+	}
+
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+
+	ln, err := listenLocal(addr)
+	if err != nil {
+		return err
+	}
+
+	var handler http.Handler = api.New(tm)
+	if ln.Addr().Network() == "tcp" {
+		handler = api.LocalOnly(handler)
+	}
+	srv := &http.Server{Handler: handler}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sigs:
+			case <-ticker.C:
+				if tm.HasSession() {
+					continue
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			srv.Shutdown(ctx)
+			cancel()
+			return
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "ccq: serving API on %s\n", ln.Addr())
+	if err := srv.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// listenLocal listens on a Unix socket ("unix:/path" or an absolute path,
+// accessible to the current user only) or a loopback TCP address. Other
+// addresses are refused: the API can switch windows and has no auth.
+func listenLocal(addr string) (net.Listener, error) {
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok || strings.HasPrefix(addr, "/") {
+		if !ok {
+			path = addr
+		}
+		// Replace a socket left behind by a previous run, never other files
+		if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.Chmod(path, 0600); err != nil {
+			ln.Close()
+			return nil, err
+		}
+		return ln, nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid listen address %q: %w", addr, err)
+	}
+	if !api.IsLoopbackHost(host) {
+		return nil, fmt.Errorf("refusing to listen on non-loopback address %q", addr)
+	}
+	return net.Listen("tcp", addr)
+}
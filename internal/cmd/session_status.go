@@ -1,21 +1,73 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
+	"text/template"
 	"time"
+	"unicode/utf8"
 
-	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/daemon"
+	"github.com/jingikim/ccq/internal/status"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
 // SessionStatus prints a detailed view of the ccq session for the terminal.
-func SessionStatus() error {
-	tm := tmux.New(sessionName)
-	output, err := renderSessionStatus(tm)
+// Usage: ccq status [--json | --format <template> | --watch [--interval <duration>]]
+//
+// --json prints the status.Session schema; --format executes a text/template
+// with a status.Session as data; --watch redraws the table in place.
+func SessionStatus(args []string) error {
+	const usage = "usage: ccq status [--json | --format <template> | --watch [--interval <duration>]]"
+
+	var asJSON, watch bool
+	var format string
+	interval := defaultWatchInterval
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--json":
+			asJSON = true
+		case args[i] == "--format" && i+1 < len(args):
+			i++
+			format = args[i]
+		case strings.HasPrefix(args[i], "--format="):
+			format = strings.TrimPrefix(args[i], "--format=")
+		case args[i] == "--watch" || args[i] == "-w":
+			watch = true
+		case args[i] == "--interval" && i+1 < len(args):
+			i++
+			d, err := time.ParseDuration(args[i])
+			if err != nil || d <= 0 {
+				return fmt.Errorf("invalid interval %q (e.g. 1s, 500ms)", args[i])
+			}
+			interval = d
+		default:
+			return fmt.Errorf(usage)
+		}
+	}
+	if (asJSON && format != "") || (watch && (asJSON || format != "")) {
+		return fmt.Errorf("--json, --format and --watch are mutually exclusive")
+	}
+	if watch {
+		return watchStatus(interval)
+	}
+
+	var output string
+	var err error
+	switch {
+	case asJSON:
+		output, err = renderSessionJSON(tmux.New(sessionName))
+	case format != "":
+		output, err = renderSessionFormat(tmux.New(sessionName), format)
+	default:
+		output, err = daemon.Call(sessionName, daemon.Request{Cmd: daemon.CmdSessionStatus})
+		if err == daemon.ErrNotRunning {
+			output, err = renderSessionStatus(tmux.New(sessionName))
+		}
+	}
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
@@ -24,52 +76,134 @@ func SessionStatus() error {
 	return nil
 }
 
-func renderSessionStatus(tm *tmux.Tmux) (string, error) {
+// loadSession reads the status model, failing if the session does not exist.
+func loadSession(tm tmux.Backend) (status.Session, error) {
 	if !tm.HasSession() {
-		return "", fmt.Errorf("ccq: no active session")
+		return status.Session{}, fmt.Errorf("ccq: no active session")
+	}
+	return status.Load(tm)
+}
+
+func renderSessionJSON(tm tmux.Backend) (string, error) {
+	sess, err := loadSession(tm)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.MarshalIndent(sess, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out) + "\n", nil
+}
+
+// formatFuncs are available to --format templates.
+var formatFuncs = template.FuncMap{
+	// ago renders a Unix timestamp as the time elapsed since then ("3m").
+	"ago": func(ts int64) string {
+		if ts <= 0 {
+			return ""
+		}
+		return status.FormatDuration(time.Since(time.Unix(ts, 0)))
+	},
+	// base returns the last element of a path, e.g. a window's project name.
+	"base": filepath.Base,
+}
+
+func renderSessionFormat(tm tmux.Backend, format string) (string, error) {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(format)
+	if err != nil {
+		return "", fmt.Errorf("invalid --format template: %w", err)
+	}
+	sess, err := loadSession(tm)
+	if err != nil {
+		return "", err
 	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, sess); err != nil {
+		return "", err
+	}
+	out := b.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		out += "\n"
+	}
+	return out, nil
+}
 
-	windows, err := tm.ListWindows()
+func renderSessionStatus(tm tmux.Backend) (string, error) {
+	sess, err := loadSession(tm)
 	if err != nil {
 		return "", err
 	}
+	return formatSessionStatus(sess, tableOptions{}), nil
+}
+
+// tableOptions adjusts the `ccq status` table for --watch.
+type tableOptions struct {
+	// queueOrder lists queued windows first, in the order auto-switch would
+	// serve them, and marks the next target with →.
+	queueOrder bool
+	// highlight holds the IDs of windows whose row is highlighted.
+	highlight map[string]bool
+}
 
-	clients := tm.ListClients()
-	autoSwitch, _ := tm.GetSessionOption("@ccq_auto_switch")
+// Row highlighting for --watch (bold yellow).
+const (
+	highlightOn  = "\x1b[1;33m"
+	highlightOff = "\x1b[0m"
+)
 
+func formatSessionStatus(sess status.Session, opts tableOptions) string {
 	// Header
 	var b strings.Builder
 
 	windowWord := "windows"
-	if len(windows) == 1 {
+	if len(sess.Windows) == 1 {
 		windowWord = "window"
 	}
 	clientWord := "clients"
-	if len(clients) == 1 {
+	if len(sess.Clients) == 1 {
 		clientWord = "client"
 	}
 
 	switchState := "off"
-	if autoSwitch == "on" {
+	if sess.AutoSwitch {
 		switchState = "on"
 	}
 
-	fmt.Fprintf(&b, "ccq: %d %s, %d %s attached, auto-switch %s\n",
-		len(windows), windowWord, len(clients), clientWord, switchState)
+	fmt.Fprintf(&b, "ccq: %d %s, %d %s attached, auto-switch %s, policy %s",
+		len(sess.Windows), windowWord, len(sess.Clients), clientWord, switchState, sess.Policy)
+	if sess.PendingSwitch != "" {
+		fmt.Fprintf(&b, ", switch pending (%s)", sess.PendingSwitch)
+	}
+	b.WriteString("\n")
 
-	if len(windows) == 0 {
-		return b.String(), nil
+	if len(sess.Windows) == 0 {
+		return b.String()
 	}
 
 	b.WriteString("\n")
 
-	// Per-window lines
+	windows := sess.Windows
+	next := ""
+	if opts.queueOrder {
+		windows, next = queueOrdered(sess)
+	}
+
+	// The git column is as wide as the longest status, and left out if no
+	// window is in a repository
+	gitWidth := 0
 	for _, w := range windows {
-		state, _ := tm.GetWindowOption(w.ID, queue.StateKey)
-		dir, _ := tm.GetWindowPanePath(w.ID)
+		if w.Git != nil {
+			gitWidth = max(gitWidth, utf8.RuneCountInString(w.Git.String()))
+		}
+	}
 
+	// Per-window lines
+	home, _ := os.UserHomeDir()
+	for _, w := range windows {
 		// Shorten home directory
-		if home, err := os.UserHomeDir(); err == nil {
+		dir := w.Dir
+		if home != "" {
 			dir = strings.Replace(dir, home, "~", 1)
 		}
 		if dir == "" {
@@ -80,23 +214,107 @@ func renderSessionStatus(tm *tmux.Tmux) (string, error) {
 		if name == "~" || name == "." || name == "" {
 			name = "~"
 		}
+		if w.Named {
+			name = w.Name
+		}
 
-		stateStr := state
+		stateStr := w.State
 		if stateStr == "" {
 			stateStr = "-"
 		}
 
 		idleStr := ""
-		if state == "idle" {
-			sinceStr, _ := tm.GetWindowOption(w.ID, queue.IdleSinceKey)
-			if ts, err := strconv.ParseInt(sinceStr, 10, 64); err == nil && ts > 0 {
-				idleStr = formatDuration(time.Since(time.Unix(ts, 0)))
+		if w.IdleSince > 0 {
+			idleStr = status.FormatDuration(time.Since(time.Unix(w.IdleSince, 0)))
+		}
+
+		var notes []string
+		if w.Profile != "" {
+			notes = append(notes, "profile "+w.Profile)
+		}
+		if w.WorktreeBranch != "" {
+			notes = append(notes, "worktree "+w.WorktreeBranch)
+		}
+		if w.SnoozedUntil > 0 {
+			notes = append(notes, "snoozed "+status.FormatDuration(time.Until(time.Unix(w.SnoozedUntil, 0))))
+		}
+		if w.Excluded {
+			notes = append(notes, "excluded")
+		}
+		note := ""
+		if len(notes) > 0 {
+			note = "   (" + strings.Join(notes, ", ") + ")"
+		}
+
+		substate := "-"
+		if w.IsIdle() && w.Substate != "" {
+			substate = w.Substate
+		}
+
+		marker := " "
+		if w.ID == next {
+			marker = "→"
+		}
+		gitCol := ""
+		if gitWidth > 0 {
+			if w.Git != nil {
+				gitCol = w.Git.String()
 			}
+			gitCol += strings.Repeat(" ", gitWidth-utf8.RuneCountInString(gitCol)+1)
 		}
+		row := fmt.Sprintf("%s #%-3s %-15s %s%-6s %-18s %-6s %6s   %s%s",
+			marker, w.Index, name, gitCol, stateStr, substate, w.Priority, idleStr, dir, note)
+		if opts.highlight[w.ID] {
+			row = highlightOn + row + highlightOff
+		}
+		b.WriteString(row + "\n")
 
-		fmt.Fprintf(&b, "  #%-3s %-15s %-6s %6s   %s\n",
-			w.Index, name, stateStr, idleStr, dir)
+		// Claude Code session mapping, recorded from hook payloads
+		if w.SessionID != "" {
+			transcript := w.TranscriptPath
+			if home != "" {
+				transcript = strings.Replace(transcript, home, "~", 1)
+			}
+			fmt.Fprintf(&b, "       session %s  %s\n", w.SessionID, transcript)
+		}
+		if w.Notification != "" {
+			fmt.Fprintf(&b, "       notification: %s\n", w.Notification)
+		}
+		if w.LastTool != "" {
+			fmt.Fprintf(&b, "       last tool: %s\n", w.LastTool)
+		}
 	}
 
-	return b.String(), nil
+	return b.String()
+}
+
+// queueOrdered returns the windows with the queue first, in serving order,
+// followed by the rest in window order, and the next auto-switch target (the
+// first queued window that is not already active).
+func queueOrdered(sess status.Session) ([]status.Window, string) {
+	byID := make(map[string]status.Window, len(sess.Windows))
+	for _, w := range sess.Windows {
+		byID[w.ID] = w
+	}
+
+	var ordered []status.Window
+	next := ""
+	queued := make(map[string]bool, len(sess.Queue))
+	for _, id := range sess.Queue {
+		w, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if next == "" && !w.Active {
+			next = id
+		}
+		queued[id] = true
+		ordered = append(ordered, w)
+	}
+	for _, w := range sess.Windows {
+		if !queued[w.ID] {
+			ordered = append(ordered, w)
+		}
+	}
+	return ordered, next
 }
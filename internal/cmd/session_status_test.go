@@ -1,23 +1,19 @@
 package cmd
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/hook"
 	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
 func TestRenderSessionStatus(t *testing.T) {
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
-	}
-
-	tm := tmux.New("ccq-test-session-status")
-	if err := tm.NewSession(); err != nil {
-		t.Fatalf("NewSession: %v", err)
-	}
-	defer tm.KillSession()
+	tm := tmux.NewFake("ccq-fake-session-status")
 
 	tm.SetSessionOption("@ccq_auto_switch", "on")
 
@@ -27,6 +23,9 @@ func TestRenderSessionStatus(t *testing.T) {
 	// Mark w0 as idle with a known timestamp
 	q := queue.New(tm)
 	q.MarkIdle(w0)
+	q.SetSubstate(w0, queue.SubstateWaitingPermission)
+	tm.SetWindowOption(w0, hook.SessionIDKey, "abc-123")
+	tm.SetWindowOption(w0, hook.TranscriptPathKey, "/tmp/abc-123.jsonl")
 
 	output, err := renderSessionStatus(tm)
 	if err != nil {
@@ -44,17 +43,102 @@ func TestRenderSessionStatus(t *testing.T) {
 	if !strings.Contains(output, "idle") {
 		t.Errorf("expected 'idle' in output, got:\n%s", output)
 	}
+	if !strings.Contains(output, "waiting_permission") {
+		t.Errorf("expected sub-state in output, got:\n%s", output)
+	}
+	if !strings.Contains(output, "session abc-123  /tmp/abc-123.jsonl") {
+		t.Errorf("expected session mapping in output, got:\n%s", output)
+	}
 }
 
 func TestRenderSessionStatus_NoSession(t *testing.T) {
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
-	}
-
-	tm := tmux.New("ccq-test-no-session-status")
+	tm := tmux.NewFake("ccq-fake-no-session-status")
+	tm.KillSession()
 
 	_, err := renderSessionStatus(tm)
 	if err == nil {
 		t.Error("expected error for non-existent session")
 	}
 }
+
+func TestRenderSessionJSON(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-json")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	q := queue.New(f)
+	q.MarkIdle(w0)
+
+	out, err := renderSessionJSON(f)
+	if err != nil {
+		t.Fatalf("renderSessionJSON: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out)
+	}
+	for _, key := range []string{"session", "auto_switch", "policy", "clients", "windows"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("missing %q in output:\n%s", key, out)
+		}
+	}
+	w := got["windows"].([]any)[0].(map[string]any)
+	for _, key := range []string{"id", "index", "dir", "state", "idle_since", "priority"} {
+		if _, ok := w[key]; !ok {
+			t.Errorf("missing window %q in output:\n%s", key, out)
+		}
+	}
+	if w["id"] != w0 || w["state"] != "idle" {
+		t.Errorf("unexpected window: %v", w)
+	}
+}
+
+func TestRenderSessionFormat(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-format")
+	w1, _ := f.NewWindow("/src/api")
+	q := queue.New(f)
+	q.MarkIdle(w1)
+
+	out, err := renderSessionFormat(f, `{{range .Windows}}{{.Index}} {{base .Dir}} {{or .State "-"}} {{ago .IdleSince}}{{"\n"}}{{end}}`)
+	if err != nil {
+		t.Fatalf("renderSessionFormat: %v", err)
+	}
+	if want := "0 / - \n1 api idle 0s\n"; out != want {
+		t.Errorf("format output =\n  %q\nwant\n  %q", out, want)
+	}
+
+	// A trailing newline is added for single-value templates
+	out, _ = renderSessionFormat(f, "{{len .Windows}}")
+	if out != "2\n" {
+		t.Errorf("expected %q, got %q", "2\n", out)
+	}
+
+	if _, err := renderSessionFormat(f, "{{.Bogus"); err == nil {
+		t.Error("expected error for invalid template")
+	}
+}
+
+func TestFormatSessionStatus_Git(t *testing.T) {
+	sess := status.Session{
+		Windows: []status.Window{
+			{ID: "@0", Index: "0", Dir: "/src/api", State: "busy", Priority: "normal", Git: &git.Status{Branch: "main"}},
+			{ID: "@1", Index: "1", Dir: "/src/api", State: "idle", Priority: "normal", Git: &git.Status{Branch: "fix", Dirty: true, Ahead: 2}},
+			{ID: "@2", Index: "2", Dir: "/tmp", State: "idle", Priority: "normal"},
+		},
+	}
+	lines := strings.Split(formatSessionStatus(sess, tableOptions{}), "\n")
+	if !strings.Contains(lines[2], " api             main   busy ") {
+		t.Errorf("expected padded branch column, got %q", lines[2])
+	}
+	if !strings.Contains(lines[3], " api             fix*⇡2 idle ") {
+		t.Errorf("expected dirty flag and ahead count, got %q", lines[3])
+	}
+	if !strings.Contains(lines[4], " tmp                    idle ") {
+		t.Errorf("expected empty branch column, got %q", lines[4])
+	}
+
+	// No column at all when no window is in a repository
+	sess.Windows = sess.Windows[2:]
+	if out := formatSessionStatus(sess, tableOptions{}); !strings.Contains(out, " tmp             idle ") {
+		t.Errorf("expected no branch column, got:\n%s", out)
+	}
+}
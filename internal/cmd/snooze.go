@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// Snooze keeps a window out of the auto-switch queue for a while.
+// Usage: ccq snooze [window] <duration|off>
+func Snooze(args []string) error {
+	var ref, arg string
+	switch len(args) {
+	case 1:
+		arg = args[0]
+	case 2:
+		ref, arg = args[0], args[1]
+	default:
+		return fmt.Errorf("usage: ccq snooze [window] <duration|off>")
+	}
+
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+
+	windowID, err := resolveWindow(tm, ref)
+	if err != nil {
+		return err
+	}
+
+	q := queue.New(tm)
+	if arg == "off" {
+		return q.Unsnooze(windowID)
+	}
+
+	d, err := time.ParseDuration(arg)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q (e.g. 20m, 1h30m)", arg)
+	}
+	if err := q.Snooze(windowID, d); err != nil {
+		return err
+	}
+	// Without the daemon, no hook fires when the snooze runs out
+	switcher.New(tm, q).RetryAfter(d)
+	return nil
+}
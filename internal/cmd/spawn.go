@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jingikim/ccq/internal/config"
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/queue"
+)
+
+// spawnTask is one window in a ccq spawn task file.
+type spawnTask struct {
+	Dir      string `json:"dir"`                // relative to the task file; ~ is expanded
+	Name     string `json:"name,omitempty"`     // window name
+	Profile  string `json:"profile,omitempty"`  // config profile
+	Worktree string `json:"worktree,omitempty"` // branch to open in a git worktree
+	Priority string `json:"priority,omitempty"` // queue priority, overriding the profile's
+	Prompt   string `json:"prompt,omitempty"`   // submitted once Claude Code is ready
+}
+
+// Spawn opens every window listed in a task file in the background. Each
+// window's prompt is submitted by the idle hook the first time the window
+// goes idle, i.e. once Claude Code is ready for input.
+// Usage: ccq spawn <tasks.json>
+func Spawn(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: ccq spawn <tasks.json>")
+	}
+	cfg, err := config.Load(config.DefaultPath())
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	tasks, err := loadTasks(args[0], cfg)
+	if err != nil {
+		return err
+	}
+
+	// Every task was checked up front, so a typo doesn't leave half the
+	// windows open
+	for i, opts := range tasks {
+		if err := launch(opts); err != nil {
+			return fmt.Errorf("task %d (%s): %w", i+1, opts.dir, err)
+		}
+	}
+	fmt.Printf("✓ spawned %d windows; prompts are submitted as each one becomes ready\n", len(tasks))
+	return nil
+}
+
+// loadTasks reads a task file (a JSON array of spawnTask) and checks every
+// task, returning the launch options for each.
+func loadTasks(path string, cfg *config.Config) ([]launchOptions, error) {
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		return nil, fmt.Errorf("task files are JSON, like the config: %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tasks []spawnTask
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tasks); err != nil {
+		return nil, fmt.Errorf("invalid task file %s: %w", path, err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("no tasks in %s", path)
+	}
+
+	base := filepath.Dir(path)
+	opts := make([]launchOptions, 0, len(tasks))
+	for i, t := range tasks {
+		if t.Dir == "" {
+			return nil, fmt.Errorf("task %d: missing dir", i+1)
+		}
+		dir := expandHome(t.Dir)
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(base, dir)
+		}
+		dir, err = launchDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+		if _, err := cfg.Profile(t.Profile); err != nil {
+			return nil, fmt.Errorf("task %d: %w", i+1, err)
+		}
+		if t.Priority != "" && !queue.ValidPriority(t.Priority) {
+			return nil, fmt.Errorf("task %d: invalid priority %q (want high, normal or low)", i+1, t.Priority)
+		}
+		if t.Worktree != "" {
+			repo, err := git.RepoRoot(dir)
+			if err == nil {
+				err = git.CheckBranch(repo, t.Worktree)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("task %d: worktree %s: %w", i+1, t.Worktree, err)
+			}
+		}
+		opts = append(opts, launchOptions{
+			dir:      dir,
+			name:     t.Name,
+			profile:  t.Profile,
+			branch:   t.Worktree,
+			priority: t.Priority,
+			// Typed into the window, where a newline would submit early
+			prompt:     strings.Join(strings.Fields(t.Prompt), " "),
+			background: true,
+		})
+	}
+	return opts, nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/config"
+	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func writeTasks(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, "tasks.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadTasks(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	base := t.TempDir()
+	os.Mkdir(filepath.Join(base, "api"), 0755)
+	other := t.TempDir()
+	if out, err := exec.Command("git", "-C", other, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	path := writeTasks(t, base, `[
+		{"dir": "api", "name": "api", "profile": "review", "priority": "high",
+		 "prompt": "Run the tests\nand fix failures"},
+		{"dir": "`+other+`", "worktree": "fix-login"}
+	]`)
+	cfg := &config.Config{Profiles: map[string]config.Profile{"review": {}}}
+
+	tasks, err := loadTasks(path, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("expected 2 tasks, got %+v", tasks)
+	}
+	want := launchOptions{
+		dir: filepath.Join(base, "api"), name: "api", profile: "review", priority: "high",
+		prompt: "Run the tests and fix failures", background: true,
+	}
+	if !reflect.DeepEqual(tasks[0], want) {
+		t.Errorf("task 1 = %+v, want %+v", tasks[0], want)
+	}
+	if got := tasks[1]; got.dir != other || got.branch != "fix-login" || !got.background {
+		t.Errorf("task 2 = %+v", got)
+	}
+
+	path = writeTasks(t, base, `[{"dir": "`+other+`", "worktree": "fix..login"}]`)
+	if _, err := loadTasks(path, cfg); err == nil || !strings.Contains(err.Error(), "task 1: worktree fix..login") {
+		t.Errorf("expected invalid branch name to be rejected, got %v", err)
+	}
+}
+
+func TestLoadTasks_Invalid(t *testing.T) {
+	base := t.TempDir()
+	cfg := &config.Config{}
+	tests := []struct {
+		name, content, want string
+	}{
+		{"empty", `[]`, "no tasks"},
+		{"typo", `[{"dir": ".", "promt": "hi"}]`, "unknown field"},
+		{"missing dir", `[{"prompt": "hi"}]`, "task 1: missing dir"},
+		{"no such dir", `[{"dir": "."}, {"dir": "nope"}]`, "task 2: not a directory"},
+		{"profile", `[{"dir": ".", "profile": "review"}]`, `unknown profile "review"`},
+		{"priority", `[{"dir": ".", "priority": "urgent"}]`, `invalid priority "urgent"`},
+		{"worktree outside a repo", `[{"dir": "."}, {"dir": ".", "worktree": "fix"}]`, "task 2: worktree fix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadTasks(writeTasks(t, base, tt.content), cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := loadTasks(filepath.Join(base, "tasks.yaml"), cfg); err == nil || !strings.Contains(err.Error(), "JSON") {
+		t.Errorf("expected YAML to be rejected, got %v", err)
+	}
+}
+
+func TestSetupWindow_PriorityAndPrompt(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-spawn")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+
+	opts := launchOptions{priority: "high", prompt: "run the tests"}
+	if err := setupWindow(f, w0, opts, config.Profile{Priority: "low"}); err != nil {
+		t.Fatal(err)
+	}
+	if p := queue.New(f).Priority(w0); p != queue.PriorityHigh {
+		t.Errorf("expected the task's priority over the profile's, got %s", p)
+	}
+	if v, _ := f.GetWindowOption(w0, hook.InitialPromptKey); v != "run the tests" {
+		t.Errorf("initial prompt = %q", v)
+	}
+}
@@ -3,17 +3,26 @@ package cmd
 import (
 	"fmt"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
+	"github.com/jingikim/ccq/internal/daemon"
 	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
 // Status prints a one-line dashboard summary of all windows.
 // Called by tmux status bar via #(ccq _status).
 func Status() error {
+	if line, err := daemon.Call(sessionName, daemon.Request{Cmd: daemon.CmdStatus}); err != daemon.ErrNotRunning {
+		if err != nil {
+			return err
+		}
+		fmt.Print(line)
+		return nil
+	}
+
 	tm := tmux.New(sessionName)
 	if !tm.HasSession() {
 		return nil
@@ -27,59 +36,92 @@ func Status() error {
 	return nil
 }
 
-func renderStatusLine(tm *tmux.Tmux) (string, error) {
-	windows, err := tm.ListWindows()
+func renderStatusLine(tm tmux.Backend) (string, error) {
+	q := queue.New(tm)
+	windows, err := q.Snapshot(status.WindowKeys...)
 	if err != nil {
 		return "", err
 	}
+	status.ScheduleGit(tm, windows)
+	return formatStatusLine(status.Windows(windows)), nil
+}
 
+// formatStatusLine renders the dashboard line. Durations are computed against
+// the current time, so a cached snapshot still renders fresh countdowns.
+func formatStatusLine(windows []status.Window) string {
 	var parts []string
 	idleCount := 0
+	snoozedCount := 0
 
 	for _, w := range windows {
-		state, _ := tm.GetWindowOption(w.ID, queue.StateKey)
-
-		dir, _ := tm.GetWindowPanePath(w.ID)
-		dirName := filepath.Base(dir)
+		dirName := filepath.Base(w.Dir)
 		if dirName == "" || dirName == "." {
 			dirName = "~"
 		}
 
-		var icon string
 		var suffix string
-
-		if w.Active {
-			icon = "▶"
-		} else {
-			switch state {
-			case "idle":
-				icon = "○"
+		if !w.Active && w.IsIdle() {
+			if w.SnoozedUntil > 0 {
+				snoozedCount++
+				suffix = " (" + status.FormatDuration(time.Until(time.Unix(w.SnoozedUntil, 0))) + ")"
+			} else {
 				idleCount++
-				sinceStr, _ := tm.GetWindowOption(w.ID, queue.IdleSinceKey)
-				if ts, err := strconv.ParseInt(sinceStr, 10, 64); err == nil && ts > 0 {
-					d := time.Since(time.Unix(ts, 0))
-					suffix = " " + formatDuration(d)
+				if w.IdleSince > 0 {
+					suffix = " " + status.FormatDuration(time.Since(time.Unix(w.IdleSince, 0)))
 				}
-			case "busy":
-				icon = "●"
-			default:
-				icon = "·"
 			}
 		}
 
-		parts = append(parts, fmt.Sprintf("%s %s:%s%s", icon, w.Index, dirName, suffix))
+		if w.IsIdle() && !w.Active {
+			suffix += waitingDetail(w)
+		}
+
+		if w.Named {
+			dirName = w.Name
+		}
+		if w.Git != nil {
+			dirName += "@" + w.Git.String()
+		}
+		if w.Profile != "" {
+			dirName += "[" + w.Profile + "]"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s:%s%s%s", w.Icon(), w.Index, dirName, w.PriorityMark()+w.ExcludedMark(), suffix))
 	}
 
 	summary := fmt.Sprintf("%d/%d idle", idleCount, len(windows))
-	return strings.Join(parts, " | ") + "    " + summary, nil
+	if snoozedCount > 0 {
+		summary += fmt.Sprintf(", %d snoozed", snoozedCount)
+	}
+	return strings.Join(parts, " | ") + "    " + summary
 }
 
-func formatDuration(d time.Duration) string {
-	if d < time.Minute {
-		return fmt.Sprintf("%ds", int(d.Seconds()))
+// statusDetailWidth caps the per-window detail shown on the dashboard line.
+const statusDetailWidth = 24
+
+// waitingDetail returns a truncated hint of what an idle window is waiting on:
+// the pending tool for permission prompts, otherwise the notification text.
+func waitingDetail(w status.Window) string {
+	var detail string
+	switch w.Substate {
+	case queue.SubstateWaitingPermission:
+		detail = w.LastTool
+		if detail == "" {
+			detail = w.Notification
+		}
+	case queue.SubstateWaitingInput:
+		detail = w.Notification
+	}
+	if detail == "" {
+		return ""
 	}
-	if d < time.Hour {
-		return fmt.Sprintf("%dm", int(d.Minutes()))
+	return " " + truncate(detail, statusDetailWidth)
+}
+
+// truncate shortens s to at most n runes, marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
 	}
-	return fmt.Sprintf("%dh", int(d.Hours()))
+	return string(r[:n-1]) + "…"
 }
@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/hook"
 	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
@@ -20,15 +25,7 @@ func TestStatusUsesQueueConstants(t *testing.T) {
 }
 
 func TestRenderStatusLine(t *testing.T) {
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
-	}
-
-	tm := tmux.New("ccq-test-status")
-	if err := tm.NewSession(); err != nil {
-		t.Fatalf("NewSession: %v", err)
-	}
-	defer tm.KillSession()
+	tm := tmux.NewFake("ccq-fake-status")
 
 	q := queue.New(tm)
 
@@ -52,8 +49,6 @@ func TestRenderStatusLine(t *testing.T) {
 	}
 	tm.SelectWindow(w2)
 
-	time.Sleep(100 * time.Millisecond)
-
 	line, err := renderStatusLine(tm)
 	if err != nil {
 		t.Fatalf("renderStatusLine: %v", err)
@@ -94,15 +89,7 @@ func TestRenderStatusLine(t *testing.T) {
 }
 
 func TestRenderStatusLineActiveWindow(t *testing.T) {
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
-	}
-
-	tm := tmux.New("ccq-test-status-active")
-	if err := tm.NewSession(); err != nil {
-		t.Fatalf("NewSession: %v", err)
-	}
-	defer tm.KillSession()
+	tm := tmux.NewFake("ccq-fake-status-active")
 
 	line, err := renderStatusLine(tm)
 	if err != nil {
@@ -120,20 +107,145 @@ func TestRenderStatusLineActiveWindow(t *testing.T) {
 	}
 }
 
-func TestFormatDuration(t *testing.T) {
-	tests := []struct {
-		d    time.Duration
-		want string
-	}{
-		{30 * time.Second, "30s"},
-		{90 * time.Second, "1m"},
-		{5 * time.Minute, "5m"},
-		{2 * time.Hour, "2h"},
-	}
-	for _, tt := range tests {
-		got := formatDuration(tt.d)
-		if got != tt.want {
-			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
-		}
+func TestRenderStatusLinePriority(t *testing.T) {
+	tm := tmux.NewFake("ccq-fake-status-priority")
+
+	q := queue.New(tm)
+	windows, _ := tm.ListWindows()
+	q.MarkIdle(windows[0].ID)
+	q.SetPriority(windows[0].ID, queue.PriorityHigh)
+
+	w1, _ := tm.NewWindow("/tmp")
+	tm.SelectWindow(w1)
+
+	line, err := renderStatusLine(tm)
+	if err != nil {
+		t.Fatalf("renderStatusLine: %v", err)
+	}
+	if !strings.Contains(line, "↑") {
+		t.Errorf("status line should mark high priority with ↑, got: %s", line)
+	}
+}
+
+func TestRenderStatusLineSnoozed(t *testing.T) {
+	tm := tmux.NewFake("ccq-fake-status-snoozed")
+
+	q := queue.New(tm)
+	windows, _ := tm.ListWindows()
+	q.MarkIdle(windows[0].ID)
+	q.Snooze(windows[0].ID, 20*time.Minute)
+
+	w1, _ := tm.NewWindow("/tmp")
+	tm.SelectWindow(w1)
+
+	line, err := renderStatusLine(tm)
+	if err != nil {
+		t.Fatalf("renderStatusLine: %v", err)
+	}
+	if !strings.Contains(line, "◌") {
+		t.Errorf("status line should contain snoozed icon ◌, got: %s", line)
+	}
+	if !strings.Contains(line, "(19m)") && !strings.Contains(line, "(20m)") {
+		t.Errorf("status line should contain snooze countdown, got: %s", line)
+	}
+	if !strings.Contains(line, "0/2 idle, 1 snoozed") {
+		t.Errorf("status line should count snoozed windows separately, got: %s", line)
+	}
+}
+
+func TestRenderStatusLineWaitingDetail(t *testing.T) {
+	tm := tmux.NewFake("ccq-fake-status-detail")
+
+	q := queue.New(tm)
+	windows, _ := tm.ListWindows()
+	w0 := windows[0].ID
+	q.MarkIdle(w0)
+	q.SetSubstate(w0, queue.SubstateWaitingPermission)
+	tm.SetWindowOption(w0, hook.LastToolKey, "Bash(rm -rf build/ && make clean all)")
+
+	w1, _ := tm.NewWindow("/tmp")
+	tm.SelectWindow(w1)
+
+	line, err := renderStatusLine(tm)
+	if err != nil {
+		t.Fatalf("renderStatusLine: %v", err)
+	}
+	if !strings.Contains(line, "◆") {
+		t.Errorf("status line should contain permission icon ◆, got: %s", line)
+	}
+	if !strings.Contains(line, "Bash(rm -rf build/ && m…") {
+		t.Errorf("status line should contain truncated pending tool, got: %s", line)
+	}
+}
+
+func TestRenderStatusLine_Format(t *testing.T) {
+	f := tmux.NewFake("ccq-fake")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+	f.SetPanePath(w0, "/src/web")
+
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	f.SetWindowOption(w1, queue.StateKey, "idle")
+	f.SetWindowOption(w1, queue.IdleSinceKey, fmt.Sprint(time.Now().Add(-3*time.Minute).Unix()))
+	q.SetPriority(w1, queue.PriorityLow)
+	f.SetWindowOption(w1, status.ProfileKey, "review")
+
+	line, err := renderStatusLine(f)
+	if err != nil {
+		t.Fatalf("renderStatusLine: %v", err)
+	}
+	want := "▶ 0:web | ○ 1:api[review]↓ 3m    1/2 idle"
+	if line != want {
+		t.Errorf("renderStatusLine =\n  %q\nwant\n  %q", line, want)
+	}
+}
+
+func TestFormatStatusLine_Git(t *testing.T) {
+	windows := []status.Window{
+		{Index: "0", Dir: "/src/api", Active: true, State: "busy", Git: &git.Status{Branch: "main"}},
+		{Index: "1", Dir: "/src/api", State: "busy", Profile: "review", Git: &git.Status{Branch: "fix", Dirty: true, Behind: 1}},
+	}
+	want := "▶ 0:api@main | ● 1:api@fix*⇣1[review]    0/2 idle"
+	if line := formatStatusLine(windows); line != want {
+		t.Errorf("formatStatusLine =\n  %q\nwant\n  %q", line, want)
+	}
+}
+
+func TestRenderStatusLine_SingleTmuxCall(t *testing.T) {
+	f := tmux.NewFake("ccq-fake")
+	q := queue.New(f)
+	for i := 0; i < 15; i++ {
+		id, _ := f.NewWindow("/tmp")
+		q.MarkIdle(id)
+	}
+	before := f.Calls("GetWindowOption")
+	writes := f.Calls("SetWindowOption")
+
+	if _, err := renderStatusLine(f); err != nil {
+		t.Fatalf("renderStatusLine: %v", err)
+	}
+	if n := f.Calls("ListWindows"); n != 1 {
+		t.Errorf("expected 1 list-windows call, got %d", n)
+	}
+	if n := f.Calls("GetWindowOption") - before; n != 0 {
+		t.Errorf("expected no per-window option reads, got %d", n)
+	}
+	// Due git statuses are read in the background, not by the render
+	if n := f.Calls("SetWindowOption") - writes; n != 0 {
+		t.Errorf("expected no per-window option writes, got %d", n)
+	}
+	if shell := f.Shell(); !reflect.DeepEqual(shell, []string{"ccq _git"}) {
+		t.Errorf("expected one background git refresh, got %q", shell)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate short = %q", got)
+	}
+	if got := truncate("Claude needs permission", 10); got != "Claude ne…" {
+		t.Errorf("truncate long = %q", got)
 	}
 }
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jingikim/ccq/internal/tmux"
+	"github.com/jingikim/ccq/internal/ui"
+)
+
+// UI runs the full-screen dashboard. It is bound to prefix + u in a popup, but
+// also works from any terminal.
+func UI() error {
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+	return ui.Run(tm)
+}
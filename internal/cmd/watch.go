@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jingikim/ccq/internal/daemon"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// defaultWatchInterval is how often `ccq status --watch` redraws without a
+// daemon, matching the status bar refresh.
+const defaultWatchInterval = 2 * time.Second
+
+// watchHighlight is how long a window's row stays highlighted after its state
+// changes.
+const watchHighlight = 5 * time.Second
+
+// watchStatus redraws the `ccq status` table in place until interrupted,
+// sorted by queue order. With a daemon running it redraws as soon as the
+// daemon sees a change, and at least every interval.
+func watchStatus(interval time.Duration) error {
+	tm := tmux.New(sessionName)
+	w := &watcher{changedAt: make(map[string]time.Time)}
+	var version uint64
+	for {
+		sess, err := loadSession(tm)
+		fmt.Print(w.frame(sess, err, interval, time.Now()))
+
+		if v, err := daemon.Wait(sessionName, version, interval); err == nil {
+			version = v
+			continue
+		}
+		time.Sleep(interval)
+	}
+}
+
+// watcher tracks state transitions between --watch frames.
+type watcher struct {
+	prev      map[string]status.Window
+	changedAt map[string]time.Time
+}
+
+// frame renders one screen: cursor home, the table with recently changed
+// windows highlighted, and the rest of the screen cleared.
+func (w *watcher) frame(sess status.Session, loadErr error, interval time.Duration, now time.Time) string {
+	var body string
+	if loadErr != nil {
+		body = loadErr.Error() + "\n"
+	} else {
+		body = formatSessionStatus(sess, tableOptions{
+			queueOrder: true,
+			highlight:  w.track(sess, now),
+		})
+	}
+
+	header := fmt.Sprintf("Every %s: ccq status    %s\n\n", interval, now.Format("15:04:05"))
+	// Clear each line's leftovers from the previous frame, then below the frame
+	out := strings.ReplaceAll(header+body, "\n", "\x1b[K\n")
+	return "\x1b[H" + out + "\x1b[J"
+}
+
+// track records windows whose state or sub-state changed since the previous
+// frame and returns those changed within watchHighlight. New windows count as
+// changed; the first frame highlights nothing.
+func (w *watcher) track(sess status.Session, now time.Time) map[string]bool {
+	cur := make(map[string]status.Window, len(sess.Windows))
+	for _, win := range sess.Windows {
+		cur[win.ID] = win
+		if w.prev == nil {
+			continue
+		}
+		old, ok := w.prev[win.ID]
+		if !ok || old.State != win.State || old.Substate != win.Substate {
+			w.changedAt[win.ID] = now
+		}
+	}
+	w.prev = cur
+
+	highlight := make(map[string]bool)
+	for id, at := range w.changedAt {
+		if _, ok := cur[id]; !ok || now.Sub(at) >= watchHighlight {
+			delete(w.changedAt, id)
+			continue
+		}
+		highlight[id] = true
+	}
+	return highlight
+}
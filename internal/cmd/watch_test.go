@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/status"
+)
+
+func TestFormatSessionStatus_QueueOrder(t *testing.T) {
+	sess := status.Session{
+		Windows: []status.Window{
+			{ID: "@0", Index: "0", Dir: "/src/web", Active: true, State: "busy", Priority: "normal"},
+			{ID: "@1", Index: "1", Dir: "/src/api", State: "idle", Priority: "normal", IdleSince: 100},
+			{ID: "@2", Index: "2", Dir: "/src/docs", State: "idle", Priority: "high", IdleSince: 200},
+		},
+		Queue: []string{"@2", "@1"},
+	}
+
+	out := formatSessionStatus(sess, tableOptions{queueOrder: true})
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("expected header, blank and 3 rows, got:\n%s", out)
+	}
+	if !strings.HasPrefix(lines[2], "→ #2 ") {
+		t.Errorf("expected next target #2 first and marked, got %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "  #1 ") || !strings.HasPrefix(lines[4], "  #0 ") {
+		t.Errorf("expected queue then remaining windows, got:\n%s", out)
+	}
+
+	// Without queueOrder the table stays in window order, unmarked
+	plain := formatSessionStatus(sess, tableOptions{})
+	if strings.Contains(plain, "→") || !strings.Contains(plain, "\n  #0 ") {
+		t.Errorf("unexpected plain table:\n%s", plain)
+	}
+}
+
+func TestQueueOrdered_ActiveNotNext(t *testing.T) {
+	sess := status.Session{
+		Windows: []status.Window{
+			{ID: "@0", Active: true, State: "idle"},
+			{ID: "@1", State: "idle"},
+		},
+		Queue: []string{"@0", "@1"},
+	}
+	if _, next := queueOrdered(sess); next != "@1" {
+		t.Errorf("expected the active window to be skipped as next target, got %q", next)
+	}
+}
+
+func TestWatcher_HighlightsTransitions(t *testing.T) {
+	w := &watcher{changedAt: make(map[string]time.Time)}
+	now := time.Unix(1000, 0)
+	sess := status.Session{Windows: []status.Window{
+		{ID: "@0", State: "busy"},
+		{ID: "@1", State: "busy"},
+	}}
+
+	if hl := w.track(sess, now); len(hl) != 0 {
+		t.Errorf("first frame should highlight nothing, got %v", hl)
+	}
+
+	sess.Windows[1].State = "idle"
+	hl := w.track(sess, now.Add(time.Second))
+	if !hl["@1"] || hl["@0"] {
+		t.Errorf("expected only @1 highlighted, got %v", hl)
+	}
+
+	if hl := w.track(sess, now.Add(time.Second+watchHighlight)); len(hl) != 0 {
+		t.Errorf("highlight should expire, got %v", hl)
+	}
+
+	frame := w.frame(sess, nil, 2*time.Second, now)
+	if !strings.HasPrefix(frame, "\x1b[HEvery 2s: ccq status") || !strings.HasSuffix(frame, "\x1b[J") {
+		t.Errorf("unexpected frame:\n%q", frame)
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// resolveWindow maps a user-supplied window reference to a window ID in the
+// ccq session. Accepts a window ID (@3), a window index (3), or "" for the
+// window the command runs in (falling back to the session's active window).
+func resolveWindow(tm tmux.Backend, ref string) (string, error) {
+	windows, err := tm.ListWindows()
+	if err != nil {
+		return "", err
+	}
+
+	if ref == "" {
+		if pane := os.Getenv("TMUX_PANE"); pane != "" {
+			if id, err := tm.WindowIDFromPane(pane); err == nil {
+				for _, w := range windows {
+					if w.ID == id {
+						return id, nil
+					}
+				}
+			}
+		}
+		return tm.ActiveWindowID()
+	}
+
+	for _, w := range windows {
+		if w.ID == ref || w.Index == ref {
+			return w.ID, nil
+		}
+	}
+	return "", fmt.Errorf("window %q not found in session %s", ref, tm.SessionName())
+}
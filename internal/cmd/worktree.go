@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// worktreeDir returns where ccq new --worktree creates the worktree for
+// branch: <root>/<repo name>/<branch>, or <repo>.worktrees/<branch> next to
+// the repository if no root is configured. Slashes in the branch are
+// flattened so feature branches don't nest.
+func worktreeDir(root, repo, branch string) string {
+	name := strings.ReplaceAll(branch, "/", "-")
+	if root == "" {
+		return filepath.Join(repo+".worktrees", name)
+	}
+	return filepath.Join(root, filepath.Base(repo), name)
+}
+
+// openWorktree returns the worktree with branch checked out in the repository
+// containing dir, creating it (and the branch, if new) when there is none.
+func openWorktree(root, dir, branch string) (string, error) {
+	repo, err := git.RepoRoot(dir)
+	if err != nil {
+		return "", fmt.Errorf("--worktree needs a git repository: %w", err)
+	}
+	worktrees, err := git.Worktrees(repo)
+	if err != nil {
+		return "", err
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == branch {
+			return wt.Path, nil
+		}
+	}
+
+	if root != "" {
+		if root, err = filepath.Abs(expandHome(root)); err != nil {
+			return "", fmt.Errorf("invalid worktree_root in config: %w", err)
+		}
+	}
+	path := worktreeDir(root, repo, branch)
+	if err := git.AddWorktree(repo, path, branch); err != nil {
+		return "", err
+	}
+	fmt.Printf("✓ created worktree %s for %s\n", path, branch)
+	return path, nil
+}
+
+// Done closes a window. With --remove, the git worktree it was opened in
+// (ccq new --worktree) is deleted too, unless it has uncommitted changes or
+// --force is given. The branch is kept.
+// Usage: ccq done [window] [--remove [--force]]
+func Done(args []string) error {
+	usage := fmt.Errorf("usage: ccq done [window] [--remove [--force]]")
+	var ref string
+	var remove, force bool
+	for _, arg := range args {
+		switch {
+		case arg == "--remove" || arg == "-r":
+			remove = true
+		case arg == "--force" || arg == "-f":
+			force = true
+		case strings.HasPrefix(arg, "-") || ref != "":
+			return usage
+		default:
+			ref = arg
+		}
+	}
+	if force && !remove {
+		return usage
+	}
+
+	tm := tmux.New(sessionName)
+	if !tm.HasSession() {
+		return fmt.Errorf("session %q not found", sessionName)
+	}
+	windowID, err := resolveWindow(tm, ref)
+	if err != nil {
+		return err
+	}
+	return closeWindow(tm, windowID, remove, force)
+}
+
+// closeWindow kills a window after removing its worktree if asked to. The
+// window goes last, since ccq done may be running inside it.
+func closeWindow(tm tmux.Backend, windowID string, remove, force bool) error {
+	path, _ := tm.GetWindowOption(windowID, status.WorktreeKey)
+	switch {
+	case remove && path == "":
+		return fmt.Errorf("window %s was not opened in a worktree", windowID)
+	case remove:
+		windows, err := tm.ListWindows(status.WorktreeKey)
+		if err != nil {
+			return err
+		}
+		for _, w := range windows {
+			if w.ID != windowID && w.Option[status.WorktreeKey] == path {
+				return fmt.Errorf("worktree %s is also open in window %s", path, w.Index)
+			}
+		}
+		if _, err := os.Stat(path); err == nil {
+			if !force {
+				dirty, err := git.Dirty(path)
+				if err != nil {
+					return err
+				}
+				if dirty {
+					return fmt.Errorf("worktree %s has uncommitted changes (commit them, or use --force to discard)", path)
+				}
+			}
+			repo, err := git.RepoRoot(path)
+			if err != nil {
+				return err
+			}
+			if err := git.RemoveWorktree(repo, path, force); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("✓ removed worktree %s\n", path)
+	case path != "":
+		fmt.Printf("✓ kept worktree %s (remove it with git worktree remove)\n", path)
+	}
+	return tm.KillWindow(windowID)
+}
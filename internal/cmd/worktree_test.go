@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/config"
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestWorktreeDir(t *testing.T) {
+	if got := worktreeDir("", "/src/api", "feature/login"); got != "/src/api.worktrees/feature-login" {
+		t.Errorf("default root: got %q", got)
+	}
+	if got := worktreeDir("/wt", "/src/api", "fix"); got != "/wt/api/fix" {
+		t.Errorf("configured root: got %q", got)
+	}
+}
+
+func TestSetupWindow_Worktree(t *testing.T) {
+	f := tmux.NewFake("ccq-fake-setup")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+
+	opts := launchOptions{branch: "fix", worktree: "/src/api.worktrees/fix"}
+	if err := setupWindow(f, w0, opts, config.Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := f.GetWindowOption(w0, status.WorktreeKey); v != opts.worktree {
+		t.Errorf("worktree = %q", v)
+	}
+	if v, _ := f.GetWindowOption(w0, status.WorktreeBranchKey); v != "fix" {
+		t.Errorf("branch = %q", v)
+	}
+}
+
+func TestOpenWorktreeAndClose(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=ccq", "-c", "user.email=ccq@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	root := t.TempDir()
+
+	path, err := openWorktree(root, repo, "fix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := worktreeDir(root, repo, "fix"); path != want {
+		t.Errorf("path = %q, want %q", path, want)
+	}
+	// Reused, also when asked from inside the worktree
+	if again, err := openWorktree(root, path, "fix"); err != nil || again != path {
+		t.Errorf("second open = %q, %v; want %s", again, err, path)
+	}
+
+	f := tmux.NewFake("ccq-fake-done")
+	plain, _ := f.NewWindow(repo)
+	w, _ := f.NewWindow(path)
+	setupWindow(f, w, launchOptions{branch: "fix", worktree: path}, config.Profile{})
+	other, _ := f.NewWindow(path)
+	setupWindow(f, other, launchOptions{branch: "fix", worktree: path}, config.Profile{})
+
+	if err := closeWindow(f, w, true, true); err == nil {
+		t.Error("expected removal refused while another window uses the worktree")
+	}
+	if err := closeWindow(f, other, false, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := closeWindow(f, plain, true, false); err == nil {
+		t.Error("expected --remove to be refused for a window without a worktree")
+	}
+
+	os.WriteFile(filepath.Join(path, "wip.txt"), []byte("wip\n"), 0644)
+	if err := closeWindow(f, w, true, false); err == nil {
+		t.Error("expected a dirty worktree to be kept")
+	}
+	if windows, _ := f.ListWindows(); len(windows) != 3 {
+		t.Errorf("expected no window closed after a refused removal, %d left", len(windows))
+	}
+
+	if err := closeWindow(f, w, true, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected worktree removed")
+	}
+	if worktrees, _ := git.Worktrees(repo); len(worktrees) != 1 {
+		t.Errorf("expected only the main worktree left, got %+v", worktrees)
+	}
+	if windows, _ := f.ListWindows(); len(windows) != 2 {
+		t.Errorf("expected the window closed, %d left", len(windows))
+	}
+}
@@ -2,12 +2,81 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 )
 
 type Config struct {
 	Prefix string `json:"prefix"`
+	Policy string `json:"policy,omitempty"` // initial scheduling policy for new sessions
+	// PrioritizePermission promotes windows blocked on a tool approval to high priority.
+	PrioritizePermission bool `json:"prioritize_permission,omitempty"`
+	// TypingGrace defers background-triggered switches for this long after the
+	// last key press (a Go duration such as "3s"; "0" disables).
+	TypingGrace string `json:"typing_grace,omitempty"`
+	// Daemon starts `ccq daemon` alongside new sessions.
+	Daemon bool `json:"daemon,omitempty"`
+	// GitStatus shows each window's git branch, dirty flag and ahead/behind
+	// counts on the dashboard and in `ccq status` (default true).
+	GitStatus *bool `json:"git_status,omitempty"`
+
+	// Command is the shell command new windows run (default "claude"), as a
+	// Go text/template with {{.Dir}} for the window's directory, shell-quoted.
+	Command string `json:"command,omitempty"`
+	// Args are appended to Command, before arguments given after `ccq --`.
+	Args []string `json:"args,omitempty"`
+	// Env sets environment variables for the command.
+	Env map[string]string `json:"env,omitempty"`
+
+	// Profiles are named launch setups, selected with `ccq new --profile`.
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// WorktreeRoot is where `ccq new --worktree` creates worktrees, in a
+	// subdirectory per repository. Unset, they go next to the repository, in
+	// "<repo>.worktrees".
+	WorktreeRoot string `json:"worktree_root,omitempty"`
+}
+
+// Profile is a named launch setup layered over the top-level launch settings.
+type Profile struct {
+	Command string            `json:"command,omitempty"` // replaces Config.Command
+	Args    []string          `json:"args,omitempty"`    // appended to Config.Args
+	Env     map[string]string `json:"env,omitempty"`     // merged over Config.Env
+	// Priority is the initial queue priority of the profile's windows.
+	Priority string `json:"priority,omitempty"`
+	// Exclude starts the profile's windows excluded from auto-switching.
+	Exclude bool `json:"exclude,omitempty"`
+}
+
+// Profile returns the named profile merged over the top-level launch
+// settings. The empty name returns the top-level settings alone.
+func (c *Config) Profile(name string) (Profile, error) {
+	p := Profile{Command: c.Command, Args: c.Args, Env: c.Env}
+	if name == "" {
+		return p, nil
+	}
+	named, ok := c.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+
+	if named.Command != "" {
+		p.Command = named.Command
+	}
+	p.Args = append(append([]string(nil), c.Args...), named.Args...)
+	if len(named.Env) > 0 {
+		p.Env = make(map[string]string, len(c.Env)+len(named.Env))
+		for k, v := range c.Env {
+			p.Env[k] = v
+		}
+		for k, v := range named.Env {
+			p.Env[k] = v
+		}
+	}
+	p.Priority = named.Priority
+	p.Exclude = named.Exclude
+	return p, nil
 }
 
 func DefaultPath() string {
@@ -3,6 +3,7 @@ package config_test
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/jingikim/ccq/internal/config"
@@ -45,3 +46,46 @@ func TestDefaultPath(t *testing.T) {
 		t.Errorf("expected %s, got %s", expected, p)
 	}
 }
+
+func TestProfile(t *testing.T) {
+	cfg := &config.Config{
+		Command: "claude",
+		Args:    []string{"--verbose"},
+		Env:     map[string]string{"A": "1", "B": "2"},
+		Profiles: map[string]config.Profile{
+			"review": {Args: []string{"--permission-mode", "plan"}, Env: map[string]string{"B": "3"}, Priority: "low"},
+			"yolo":   {Command: "sandbox claude", Exclude: true},
+		},
+	}
+
+	base, err := cfg.Profile("")
+	if err != nil || base.Command != "claude" || !reflect.DeepEqual(base.Args, []string{"--verbose"}) {
+		t.Errorf("unexpected base profile %+v, %v", base, err)
+	}
+
+	review, _ := cfg.Profile("review")
+	if review.Command != "claude" {
+		t.Errorf("expected the top-level command, got %q", review.Command)
+	}
+	if want := []string{"--verbose", "--permission-mode", "plan"}; !reflect.DeepEqual(review.Args, want) {
+		t.Errorf("args = %q, want %q", review.Args, want)
+	}
+	if want := map[string]string{"A": "1", "B": "3"}; !reflect.DeepEqual(review.Env, want) {
+		t.Errorf("env = %v, want %v", review.Env, want)
+	}
+	if review.Priority != "low" || review.Exclude {
+		t.Errorf("unexpected defaults %+v", review)
+	}
+	if cfg.Env["B"] != "2" {
+		t.Error("expected the top-level env to be left untouched")
+	}
+
+	yolo, _ := cfg.Profile("yolo")
+	if yolo.Command != "sandbox claude" || !yolo.Exclude {
+		t.Errorf("unexpected yolo profile %+v", yolo)
+	}
+
+	if _, err := cfg.Profile("missing"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
@@ -0,0 +1,184 @@
+// Package daemon implements the socket protocol of the optional ccq daemon: a
+// long-running process that serves hooks and status requests for one session
+// over a Unix socket, so they need not each spawn tmux commands. Clients fall
+// back to working on tmux options directly when no daemon is listening.
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/lock"
+)
+
+// Request commands.
+const (
+	CmdHook          = "hook"           // handle a Claude Code hook (Action, Pane, Payload)
+	CmdStatus        = "status"         // render the dashboard line
+	CmdSessionStatus = "session_status" // render `ccq status`
+	CmdWait          = "wait"           // block until the session changes after Version (see Wait)
+)
+
+// dialTimeout bounds how long a client waits to reach the daemon before
+// falling back. Connecting to a live local socket takes microseconds.
+const dialTimeout = 200 * time.Millisecond
+
+// callTimeout bounds a whole request, staying under the 5-second hook timeout.
+const callTimeout = 4 * time.Second
+
+// MaxWait caps how long a CmdWait request blocks, keeping it under callTimeout.
+const MaxWait = 3 * time.Second
+
+// ErrNotRunning is returned by Call when no daemon is listening.
+var ErrNotRunning = errors.New("ccq daemon not running")
+
+// Request is one client request, sent as a single JSON line.
+type Request struct {
+	Cmd     string       `json:"cmd"`
+	Action  string       `json:"action,omitempty"`
+	Pane    string       `json:"pane,omitempty"`
+	Payload hook.Payload `json:"payload"`
+
+	// CmdWait: the last version seen and how long to wait for a newer one.
+	Version uint64        `json:"version,omitempty"`
+	Timeout time.Duration `json:"timeout,omitempty"`
+}
+
+// Response is the daemon's reply, sent as a single JSON line.
+type Response struct {
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// HandlerFunc serves one request, returning the output to send back.
+type HandlerFunc func(req Request) (string, error)
+
+// SocketPath returns the daemon socket path for a session.
+func SocketPath(session string) string {
+	return filepath.Join(lock.RuntimeDir(), session+".sock")
+}
+
+// Call sends req to the session's daemon and returns its output.
+// Returns ErrNotRunning if no daemon accepts the connection.
+func Call(session string, req Request) (string, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(session), dialTimeout)
+	if err != nil {
+		return "", ErrNotRunning
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(callTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return "", fmt.Errorf("daemon request: %w", err)
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return "", fmt.Errorf("daemon response: %w", err)
+	}
+	if resp.Error != "" {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Output, nil
+}
+
+// Wait blocks until the daemon's view of the session changes after version, or
+// timeout (capped at MaxWait) passes, and returns the current version. The
+// version counts changes seen by the daemon; it starts over when the daemon
+// restarts, so callers should treat any difference as a change.
+func Wait(session string, version uint64, timeout time.Duration) (uint64, error) {
+	out, err := Call(session, Request{Cmd: CmdWait, Version: version, Timeout: timeout})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(out, 10, 64)
+}
+
+// Server accepts connections on a session's daemon socket.
+type Server struct {
+	ln   net.Listener
+	path string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Listen creates the daemon socket for a session. A socket left behind by a
+// daemon that died is replaced; a live one is an error.
+func Listen(session string) (*Server, error) {
+	path := SocketPath(session)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		conn.Close()
+		return nil, fmt.Errorf("ccq daemon already running on %s", path)
+	}
+	os.Remove(path)
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{ln: ln, path: path}, nil
+}
+
+// Path returns the socket path the server listens on.
+func (s *Server) Path() string {
+	return s.path
+}
+
+// Serve accepts connections until Close, handling each with h. Requests are
+// handled concurrently; h must serialize access to shared state.
+// Returns nil after Close.
+func (s *Server) Serve(h HandlerFunc) error {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn, h)
+	}
+}
+
+func (s *Server) handle(conn net.Conn, h HandlerFunc) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(callTimeout))
+
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		return
+	}
+	var resp Response
+	out, err := h(req)
+	if err != nil {
+		resp.Error = err.Error()
+	} else {
+		resp.Output = out
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// Close stops accepting connections and removes the socket.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	err := s.ln.Close()
+	os.Remove(s.path)
+	return err
+}
@@ -0,0 +1,87 @@
+package daemon_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/daemon"
+	"github.com/jingikim/ccq/internal/hook"
+)
+
+func TestCall_NotRunning(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	if _, err := daemon.Call("ccq-test", daemon.Request{Cmd: daemon.CmdStatus}); err != daemon.ErrNotRunning {
+		t.Errorf("expected ErrNotRunning, got %v", err)
+	}
+}
+
+func TestServe_RoundTrip(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	srv, err := daemon.Listen("ccq-test")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	done := make(chan error)
+	go func() {
+		done <- srv.Serve(func(req daemon.Request) (string, error) {
+			if req.Cmd == daemon.CmdHook {
+				return "", errors.New("boom")
+			}
+			return fmt.Sprintf("%s %s %s", req.Cmd, req.Pane, req.Payload.SessionID), nil
+		})
+	}()
+
+	out, err := daemon.Call("ccq-test", daemon.Request{
+		Cmd:     daemon.CmdStatus,
+		Pane:    "%3",
+		Payload: hook.Payload{SessionID: "abc"},
+	})
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if out != "status %3 abc" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	// Handler errors are returned to the client, not treated as "not running"
+	_, err = daemon.Call("ccq-test", daemon.Request{Cmd: daemon.CmdHook})
+	if err == nil || err == daemon.ErrNotRunning || err.Error() != "boom" {
+		t.Errorf("expected handler error, got %v", err)
+	}
+
+	// A second daemon for the same session is refused
+	if _, err := daemon.Listen("ccq-test"); err == nil {
+		t.Error("expected Listen to fail while a daemon is running")
+	}
+
+	if err := srv.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve: %v", err)
+	}
+	if _, err := os.Stat(srv.Path()); !os.IsNotExist(err) {
+		t.Errorf("expected socket to be removed, got %v", err)
+	}
+}
+
+func TestListen_ReplacesStaleSocket(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	path := daemon.SocketPath("ccq-test")
+	os.MkdirAll(filepath.Dir(path), 0700)
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	srv, err := daemon.Listen("ccq-test")
+	if err != nil {
+		t.Fatalf("Listen over stale socket: %v", err)
+	}
+	srv.Close()
+}
@@ -0,0 +1,3 @@
+// Package git reads the status of the repositories ccq windows run in, and
+// manages the worktrees `ccq new --worktree` opens windows in.
+package git
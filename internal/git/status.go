@@ -0,0 +1,72 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Status is the state of a working tree shown next to the windows in it.
+type Status struct {
+	// Branch is the checked-out branch, or the short commit hash for a
+	// detached HEAD.
+	Branch string `json:"branch"`
+	Dirty  bool   `json:"dirty"`           // uncommitted changes or untracked files
+	Ahead  int    `json:"ahead,omitempty"` // commits not on the upstream branch
+	Behind int    `json:"behind,omitempty"`
+}
+
+// String renders the status compactly, prompt-style: "main*⇡2⇣1".
+func (s Status) String() string {
+	out := s.Branch
+	if s.Dirty {
+		out += "*"
+	}
+	if s.Ahead > 0 {
+		out += fmt.Sprintf("⇡%d", s.Ahead)
+	}
+	if s.Behind > 0 {
+		out += fmt.Sprintf("⇣%d", s.Behind)
+	}
+	return out
+}
+
+// ReadStatus reads the status of the working tree containing dir with a
+// single git process.
+func ReadStatus(dir string) (Status, error) {
+	out, err := run(dir, "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Status{}, err
+	}
+	return parseStatus(out), nil
+}
+
+// parseStatus parses `git status --porcelain=v2 --branch` output.
+func parseStatus(out string) Status {
+	var s Status
+	var oid string
+	for _, line := range strings.Split(out, "\n") {
+		header, ok := strings.CutPrefix(line, "# ")
+		if !ok {
+			if line != "" {
+				s.Dirty = true
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(header, " ")
+		switch key {
+		case "branch.oid":
+			oid = value
+		case "branch.head":
+			s.Branch = value
+		case "branch.ab":
+			fmt.Sscanf(value, "+%d -%d", &s.Ahead, &s.Behind)
+		}
+	}
+	if s.Branch == "(detached)" {
+		s.Branch = ""
+		if len(oid) >= 7 {
+			s.Branch = oid[:7]
+		}
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/git"
+)
+
+func TestReadStatus(t *testing.T) {
+	t.Parallel()
+	repo := initRepo(t)
+	gitCmd := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-C", repo, "-c", "user.name=ccq", "-c", "user.email=ccq@example.com"}, args...)
+		if out, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	if s, err := git.ReadStatus(repo); err != nil || s != (git.Status{Branch: "main"}) {
+		t.Errorf("clean: got %+v, %v", s, err)
+	}
+
+	gitCmd("branch", "upstream")
+	gitCmd("commit", "-q", "--allow-empty", "-m", "one")
+	gitCmd("commit", "-q", "--allow-empty", "-m", "two")
+	gitCmd("branch", "-q", "--set-upstream-to", "upstream")
+	os.WriteFile(filepath.Join(repo, "notes.txt"), []byte("wip\n"), 0644)
+	if s, _ := git.ReadStatus(repo); s != (git.Status{Branch: "main", Dirty: true, Ahead: 2}) {
+		t.Errorf("dirty and ahead: got %+v", s)
+	}
+
+	gitCmd("checkout", "-q", "--detach")
+	if s, _ := git.ReadStatus(repo); len(s.Branch) != 7 {
+		t.Errorf("detached: expected a short hash, got %+v", s)
+	}
+
+	if _, err := git.ReadStatus(t.TempDir()); err == nil {
+		t.Error("expected an error outside a repository")
+	}
+}
+
+func TestStatusString(t *testing.T) {
+	tests := []struct {
+		s    git.Status
+		want string
+	}{
+		{git.Status{Branch: "main"}, "main"},
+		{git.Status{Branch: "main", Dirty: true}, "main*"},
+		{git.Status{Branch: "fix", Ahead: 2, Behind: 1}, "fix⇡2⇣1"},
+		{git.Status{Branch: "fix", Dirty: true, Behind: 3}, "fix*⇣3"},
+	}
+	for _, tt := range tests {
+		if got := tt.s.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
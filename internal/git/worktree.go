@@ -0,0 +1,102 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Worktree is a working tree of a repository.
+type Worktree struct {
+	Path   string
+	Branch string // "" for a detached HEAD
+}
+
+// run runs git in dir and returns its trimmed output. Errors carry git's own
+// message. Optional locks are skipped, so reading the status never gets in
+// the way of git commands running in a window.
+func run(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir, "--no-optional-locks"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RepoRoot returns the main working tree of the repository containing dir,
+// also when dir is in one of its linked worktrees.
+func RepoRoot(dir string) (string, error) {
+	common, err := run(dir, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(dir, common)
+	}
+	return filepath.Dir(filepath.Clean(common)), nil
+}
+
+// CheckBranch returns an error if branch is not a valid branch name.
+func CheckBranch(repo, branch string) error {
+	_, err := run(repo, "check-ref-format", "--branch", branch)
+	return err
+}
+
+// Worktrees lists the working trees of the repository at repo, the main one
+// first.
+func Worktrees(repo string) ([]Worktree, error) {
+	out, err := run(repo, "worktree", "list", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	var worktrees []Worktree
+	for _, line := range strings.Split(out, "\n") {
+		if path, ok := strings.CutPrefix(line, "worktree "); ok {
+			worktrees = append(worktrees, Worktree{Path: path})
+		} else if ref, ok := strings.CutPrefix(line, "branch "); ok && len(worktrees) > 0 {
+			worktrees[len(worktrees)-1].Branch = strings.TrimPrefix(ref, "refs/heads/")
+		}
+	}
+	return worktrees, nil
+}
+
+// AddWorktree checks out branch in a new worktree at path, creating the
+// branch from the current HEAD if it does not exist.
+func AddWorktree(repo, path, branch string) error {
+	args := []string{"worktree", "add", path, branch}
+	if _, err := run(repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		args = []string{"worktree", "add", "-b", branch, path}
+	}
+	_, err := run(repo, args...)
+	return err
+}
+
+// RemoveWorktree deletes the worktree at path. Unless force is set, git
+// refuses if it has uncommitted changes or untracked files. The branch is
+// kept.
+func RemoveWorktree(repo, path string, force bool) error {
+	args := []string{"worktree", "remove", path}
+	if force {
+		args = []string{"worktree", "remove", "--force", path}
+	}
+	_, err := run(repo, args...)
+	return err
+}
+
+// Dirty reports whether the working tree containing dir has uncommitted
+// changes or untracked files.
+func Dirty(dir string) (bool, error) {
+	out, err := run(dir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
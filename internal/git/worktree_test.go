@@ -0,0 +1,85 @@
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/git"
+)
+
+// initRepo creates a repository with one commit on main.
+func initRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"-c", "user.name=ccq", "-c", "user.email=ccq@example.com", "commit", "-q", "--allow-empty", "-m", "init"},
+	} {
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	return repo
+}
+
+func TestWorktrees_AddAndRemove(t *testing.T) {
+	t.Parallel()
+	repo := initRepo(t)
+	path := filepath.Join(t.TempDir(), "fix-bug")
+
+	if err := git.AddWorktree(repo, path, "fix-bug"); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := git.ReadStatus(path); err != nil || s.Branch != "fix-bug" {
+		t.Errorf("ReadStatus(worktree) = %+v, %v", s, err)
+	}
+	if root, err := git.RepoRoot(path); err != nil || root != repo {
+		t.Errorf("RepoRoot(worktree) = %q, %v; want %s", root, err, repo)
+	}
+
+	worktrees, err := git.Worktrees(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worktrees) != 2 || worktrees[0].Branch != "main" || worktrees[1] != (git.Worktree{Path: path, Branch: "fix-bug"}) {
+		t.Errorf("unexpected worktrees %+v", worktrees)
+	}
+
+	if dirty, err := git.Dirty(path); err != nil || dirty {
+		t.Errorf("Dirty(clean) = %v, %v", dirty, err)
+	}
+	os.WriteFile(filepath.Join(path, "notes.txt"), []byte("wip\n"), 0644)
+	if dirty, _ := git.Dirty(path); !dirty {
+		t.Error("expected untracked file to make the worktree dirty")
+	}
+	if err := git.RemoveWorktree(repo, path, false); err == nil {
+		t.Error("expected removing a dirty worktree to fail without force")
+	}
+	if err := git.RemoveWorktree(repo, path, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected worktree directory removed")
+	}
+
+	// The branch survives, and is checked out again rather than recreated
+	if err := git.AddWorktree(repo, path, "fix-bug"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepoRoot_NotARepo(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	if _, err := git.RepoRoot(t.TempDir()); err == nil {
+		t.Error("expected an error outside a repository")
+	}
+}
@@ -4,86 +4,178 @@ package hook
 import (
 	"strings"
 
+	"github.com/jingikim/ccq/internal/lock"
 	"github.com/jingikim/ccq/internal/queue"
 	"github.com/jingikim/ccq/internal/switcher"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
+// InitialPromptKey is the window option holding a prompt to submit the first
+// time the window goes idle, once Claude Code is ready for input (ccq spawn).
+const InitialPromptKey = "@ccq_initial_prompt"
+
 // Handler processes hook events from Claude Code.
 type Handler struct {
-	tm *tmux.Tmux
+	tm tmux.Backend
 	q  *queue.Queue
 	sw *switcher.Switcher
 }
 
-// New creates a Handler with the given tmux session, queue, and switcher.
-func New(tm *tmux.Tmux, q *queue.Queue, sw *switcher.Switcher) *Handler {
+// New creates a Handler with the given tmux backend, queue, and switcher.
+func New(tm tmux.Backend, q *queue.Queue, sw *switcher.Switcher) *Handler {
 	return &Handler{tm: tm, q: q, sw: sw}
 }
 
-// HandleIdle marks a window as idle, queuing it for the next auto-switch.
+// acquire takes the session lock so this handler's read-decide-switch sequence
+// is not interleaved with other hook processes. The wait is bounded by
+// lock.DefaultTimeout; on timeout locked is false and the handler still
+// records state but must not auto-switch (see trySwitch).
+func (h *Handler) acquire() (release func(), locked bool) {
+	l, err := lock.Acquire(h.tm.SessionName(), lock.DefaultTimeout)
+	if err != nil {
+		return func() {}, false
+	}
+	return l.Release, true
+}
+
+// trySwitch runs the auto-switch decision for a hook fired by windowID. Without
+// the lock the decision is deferred instead: the process holding the lock may
+// have read the session before this handler's state change.
+func (h *Handler) trySwitch(windowID string, locked bool) {
+	if !locked {
+		h.sw.DeferSwitch(switcher.PendingLocked, switcher.LockRetryDelay)
+		return
+	}
+	h.sw.TrySwitchLocked(windowID)
+}
+
+// HandleIdle marks a window as idle with the sub-state implied by the payload,
+// queuing it for the next auto-switch.
+// If the window has an initial prompt waiting (InitialPromptKey), it is
+// submitted instead and the window is not queued, since it is about to go busy.
 // If the window has @ccq_return_to set (initial setup after ccq add),
 // it switches back to the previous window or detaches the client instead.
 //
 // TrySwitch after marking idle: if the active window is busy, switch to the
 // oldest idle window immediately. If the active window is also idle, the
 // newly idle window just waits in the queue.
-func (h *Handler) HandleIdle(windowID string) error {
+func (h *Handler) HandleIdle(windowID string, p Payload) error {
+	h.recordSession(windowID, p)
+	if p.HookEventName == "Notification" && p.Message != "" {
+		// Collapse whitespace: option values are read back tab/newline-separated.
+		h.tm.SetWindowOption(windowID, NotificationKey, strings.Join(strings.Fields(p.Message), " "))
+	}
+	substate := p.Substate()
+
+	release, locked := h.acquire()
+	defer release()
+
+	if prompt, _ := h.tm.GetWindowOption(windowID, InitialPromptKey); prompt != "" {
+		h.tm.UnsetWindowOption(windowID, InitialPromptKey)
+		return h.tm.SendKeys(windowID, prompt, true)
+	}
+
 	returnTo, _ := h.tm.GetWindowOption(windowID, "@ccq_return_to")
 	if returnTo != "" {
 		h.tm.UnsetWindowOption(windowID, "@ccq_return_to")
-		if err := h.q.MarkIdle(windowID); err != nil {
+		if err := h.markIdle(windowID, substate); err != nil {
 			return err
 		}
 		if returnTo == "__detach__" {
-			h.tm.Run("detach-client", "-s", h.tm.Session)
+			h.tm.DetachClient("")
 		} else if strings.HasPrefix(returnTo, "__detach__:") {
-			tty := strings.TrimPrefix(returnTo, "__detach__:")
-			h.tm.Run("detach-client", "-t", tty)
+			h.tm.DetachClient(strings.TrimPrefix(returnTo, "__detach__:"))
 		} else {
-			h.tm.SelectWindow(returnTo)
+			h.sw.Focus(returnTo, switcher.ReasonReturn)
 		}
 		return nil
 	}
 
-	if err := h.q.MarkIdle(windowID); err != nil {
+	if err := h.markIdle(windowID, substate); err != nil {
 		return err
 	}
-	h.sw.TrySwitch()
+	h.trySwitch(windowID, locked)
 	return nil
 }
 
-// HandleBusy marks a window as busy and triggers auto-switch, but only if the
+// markIdle marks a window idle and records its sub-state. An empty substate
+// keeps the existing one (e.g. idle_prompt re-notifying a finished window).
+func (h *Handler) markIdle(windowID, substate string) error {
+	if err := h.q.MarkIdle(windowID); err != nil {
+		return err
+	}
+	if substate == "" {
+		return nil
+	}
+	return h.q.SetSubstate(windowID, substate)
+}
+
+// recordSession stores the Claude Code session ID and transcript path on the
+// window so it can be mapped back to its session.
+func (h *Handler) recordSession(windowID string, p Payload) {
+	if p.SessionID != "" {
+		h.tm.SetWindowOption(windowID, SessionIDKey, p.SessionID)
+	}
+	if p.TranscriptPath != "" {
+		h.tm.SetWindowOption(windowID, TranscriptPathKey, p.TranscriptPath)
+	}
+}
+
+// HandleBusy records the tool about to run (shown while a permission prompt is
+// pending), then marks a window as busy and triggers auto-switch, but only if the
 // window was idle (e.g., the user just answered a permission prompt or elicitation
-// dialog). If the window is already busy, this is a no-op — avoids redundant
-// state writes and unwanted switches during normal tool execution.
-func (h *Handler) HandleBusy(windowID string) error {
+// dialog). If the window is already busy, the state is left alone — avoids
+// redundant state writes and unwanted switches during normal tool execution.
+func (h *Handler) HandleBusy(windowID string, p Payload) error {
+	if tool := p.ToolSummary(); tool != "" {
+		h.tm.SetWindowOption(windowID, LastToolKey, tool)
+	}
 	if !h.q.IsIdle(windowID) {
 		return nil
 	}
+
+	release, locked := h.acquire()
+	defer release()
+
+	h.tm.UnsetWindowOption(windowID, NotificationKey)
 	if err := h.q.MarkBusy(windowID); err != nil {
 		return err
 	}
-	h.sw.TrySwitch()
+	h.trySwitch(windowID, locked)
 	return nil
 }
 
 // HandlePromptSubmit marks a window as busy (overriding idle state) and attempts auto-switch.
+// The previous turn's notification and last tool are cleared.
 // Used for UserPromptSubmit hook - when user submits a prompt, the window transitions
 // from idle to busy, so we should always mark as busy and switch.
-func (h *Handler) HandlePromptSubmit(windowID string) error {
+func (h *Handler) HandlePromptSubmit(windowID string, p Payload) error {
+	h.recordSession(windowID, p)
+	h.tm.UnsetWindowOption(windowID, NotificationKey)
+	h.tm.UnsetWindowOption(windowID, LastToolKey)
+
+	release, locked := h.acquire()
+	defer release()
+
 	if err := h.q.MarkBusy(windowID); err != nil {
 		return err
 	}
-	h.sw.TrySwitch()
+	h.trySwitch(windowID, locked)
 	return nil
 }
 
 // HandleRemove clears all ccq-related window options for a removed window.
 // Uses UnsetWindowOption to properly remove variables. Errors are ignored
 // because the window may already be gone (remain-on-exit off).
-func (h *Handler) HandleRemove(windowID string) error {
-	_ = h.tm.UnsetWindowOption(windowID, "@ccq_state")
-	_ = h.tm.UnsetWindowOption(windowID, "@ccq_idle_since")
+func (h *Handler) HandleRemove(windowID string, p Payload) error {
+	_ = h.tm.UnsetWindowOption(windowID, queue.StateKey)
+	_ = h.tm.UnsetWindowOption(windowID, queue.IdleSinceKey)
+	_ = h.tm.UnsetWindowOption(windowID, queue.SnoozedUntilKey)
+	_ = h.tm.UnsetWindowOption(windowID, queue.SubstateKey)
+	_ = h.tm.UnsetWindowOption(windowID, SessionIDKey)
+	_ = h.tm.UnsetWindowOption(windowID, TranscriptPathKey)
+	_ = h.tm.UnsetWindowOption(windowID, NotificationKey)
+	_ = h.tm.UnsetWindowOption(windowID, LastToolKey)
+	_ = h.tm.UnsetWindowOption(windowID, InitialPromptKey)
 	return nil
 }
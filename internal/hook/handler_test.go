@@ -1,38 +1,36 @@
 package hook_test
 
 import (
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/lock"
 	"github.com/jingikim/ccq/internal/queue"
 	"github.com/jingikim/ccq/internal/switcher"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
-func setup(t *testing.T, name string) (*tmux.Tmux, *queue.Queue, *switcher.Switcher, func()) {
+func setup(t *testing.T) (*tmux.Fake, *queue.Queue, *switcher.Switcher) {
 	t.Helper()
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
-	}
-	tm := tmux.New(name)
-	if err := tm.NewSession(); err != nil {
-		t.Fatalf("NewSession: %v", err)
-	}
-	q := queue.New(tm)
-	sw := switcher.New(tm, q)
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-" + t.Name())
+	q := queue.New(f)
+	sw := switcher.New(f, q)
 	sw.SetAutoSwitch(true)
-	return tm, q, sw, func() { tm.KillSession() }
+	return f, q, sw
 }
 
 func TestHandleIdle_MarksWindowIdle(t *testing.T) {
-	tm, q, sw, cleanup := setup(t, "ccq-test-hook-idle")
-	defer cleanup()
+	f, q, sw := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
 
-	h := hook.New(tm, q, sw)
-	if err := h.HandleIdle(w0); err != nil {
+	h := hook.New(f, q, sw)
+	if err := h.HandleIdle(w0, hook.Payload{}); err != nil {
 		t.Fatalf("HandleIdle: %v", err)
 	}
 
@@ -42,45 +40,43 @@ func TestHandleIdle_MarksWindowIdle(t *testing.T) {
 }
 
 func TestHandleBusy_SkipsWhenAlreadyBusy(t *testing.T) {
-	tm, q, sw, cleanup := setup(t, "ccq-test-hook-busy-noop")
-	defer cleanup()
+	f, q, sw := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	// w0 = active + busy (normal tool execution), w1 = idle
 	q.MarkIdle(w1)
-	tm.SelectWindow(w0)
+	f.SelectWindow(w0)
 
-	h := hook.New(tm, q, sw)
-	if err := h.HandleBusy(w0); err != nil {
+	h := hook.New(f, q, sw)
+	if err := h.HandleBusy(w0, hook.Payload{}); err != nil {
 		t.Fatalf("HandleBusy: %v", err)
 	}
 
 	// Already busy → no-op: should NOT switch
-	activeID, _ := tm.ActiveWindowID()
+	activeID, _ := f.ActiveWindowID()
 	if activeID != w0 {
 		t.Errorf("HandleBusy should not switch when already busy, got active=%s", activeID)
 	}
 }
 
 func TestHandleBusy_SwitchesWhenIdle(t *testing.T) {
-	tm, q, sw, cleanup := setup(t, "ccq-test-hook-busy-switch")
-	defer cleanup()
+	f, q, sw := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	// Simulate permission grant: w0 = active + idle (permission_prompt answered),
 	// w1 = idle (another window waiting)
 	q.MarkIdle(w0)
 	q.MarkIdle(w1)
-	tm.SelectWindow(w0)
+	f.SelectWindow(w0)
 
-	h := hook.New(tm, q, sw)
-	if err := h.HandleBusy(w0); err != nil {
+	h := hook.New(f, q, sw)
+	if err := h.HandleBusy(w0, hook.Payload{}); err != nil {
 		t.Fatalf("HandleBusy: %v", err)
 	}
 
@@ -88,19 +84,18 @@ func TestHandleBusy_SwitchesWhenIdle(t *testing.T) {
 	if q.IsIdle(w0) {
 		t.Error("w0 should be busy after HandleBusy on idle window")
 	}
-	activeID, _ := tm.ActiveWindowID()
+	activeID, _ := f.ActiveWindowID()
 	if activeID != w1 {
 		t.Errorf("expected switch to %s (oldest idle), got active=%s", w1, activeID)
 	}
 }
 
 func TestHandlePromptSubmit_SwitchesFromIdleWindow(t *testing.T) {
-	tm, q, sw, cleanup := setup(t, "ccq-test-prompt-submit")
-	defer cleanup()
+	f, q, sw := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	// Simulate UserPromptSubmit scenario:
 	// 1. w0 = idle (user is at idle_prompt)
@@ -108,10 +103,10 @@ func TestHandlePromptSubmit_SwitchesFromIdleWindow(t *testing.T) {
 	// 3. User submits prompt in w0 → HandlePromptSubmit(w0) should mark busy and switch to w1
 	q.MarkIdle(w0)
 	q.MarkIdle(w1)
-	tm.SelectWindow(w0)
+	f.SelectWindow(w0)
 
-	h := hook.New(tm, q, sw)
-	if err := h.HandlePromptSubmit(w0); err != nil {
+	h := hook.New(f, q, sw)
+	if err := h.HandlePromptSubmit(w0, hook.Payload{}); err != nil {
 		t.Fatalf("HandlePromptSubmit: %v", err)
 	}
 
@@ -121,24 +116,23 @@ func TestHandlePromptSubmit_SwitchesFromIdleWindow(t *testing.T) {
 	}
 
 	// Should switch to w1 (oldest idle)
-	activeID, _ := tm.ActiveWindowID()
+	activeID, _ := f.ActiveWindowID()
 	if activeID != w1 {
 		t.Errorf("expected switch to %s (oldest idle), got active=%s", w1, activeID)
 	}
 }
 
 func TestHandleIdle_ReturnToDetach(t *testing.T) {
-	tm, q, sw, cleanup := setup(t, "ccq-test-hook-detach")
-	defer cleanup()
+	f, q, sw := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
 
 	// Set return_to = "__detach__" (no tty)
-	tm.SetWindowOption(w0, "@ccq_return_to", "__detach__")
+	f.SetWindowOption(w0, "@ccq_return_to", "__detach__")
 
-	h := hook.New(tm, q, sw)
-	if err := h.HandleIdle(w0); err != nil {
+	h := hook.New(f, q, sw)
+	if err := h.HandleIdle(w0, hook.Payload{}); err != nil {
 		t.Fatalf("HandleIdle: %v", err)
 	}
 
@@ -148,26 +142,25 @@ func TestHandleIdle_ReturnToDetach(t *testing.T) {
 	}
 
 	// return_to should be cleared
-	val, _ := tm.GetWindowOption(w0, "@ccq_return_to")
+	val, _ := f.GetWindowOption(w0, "@ccq_return_to")
 	if val != "" {
 		t.Errorf("expected @ccq_return_to to be cleared, got %q", val)
 	}
 }
 
 func TestHandleIdle_ReturnToWindow(t *testing.T) {
-	tm, q, sw, cleanup := setup(t, "ccq-test-hook-return")
-	defer cleanup()
+	f, q, sw := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	// Set return_to = w0 (return to previous window)
-	tm.SetWindowOption(w1, "@ccq_return_to", w0)
-	tm.SelectWindow(w1)
+	f.SetWindowOption(w1, "@ccq_return_to", w0)
+	f.SelectWindow(w1)
 
-	h := hook.New(tm, q, sw)
-	if err := h.HandleIdle(w1); err != nil {
+	h := hook.New(f, q, sw)
+	if err := h.HandleIdle(w1, hook.Payload{}); err != nil {
 		t.Fatalf("HandleIdle: %v", err)
 	}
 
@@ -177,26 +170,259 @@ func TestHandleIdle_ReturnToWindow(t *testing.T) {
 	}
 
 	// Should have switched back to w0
-	activeID, _ := tm.ActiveWindowID()
+	activeID, _ := f.ActiveWindowID()
 	if activeID != w0 {
 		t.Errorf("expected switch to %s, got active=%s", w0, activeID)
 	}
 }
 
+func TestHandleIdle_ActiveIdleQueues(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	q.MarkIdle(w0) // user is at the prompt in w0
+	h := hook.New(f, q, sw)
+	h.HandleIdle(w1, hook.Payload{HookEventName: "Stop"})
+
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Errorf("expected to stay on idle active window, got %s", active)
+	}
+}
+
+func TestHandleIdle_ReturnToDetachesClient(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	f.AttachClient("/dev/ttys001")
+	f.AttachClient("/dev/ttys002")
+	f.SetWindowOption(w0, "@ccq_return_to", "__detach__:/dev/ttys002")
+
+	h := hook.New(f, q, sw)
+	h.HandleIdle(w0, hook.Payload{HookEventName: "Notification", NotificationType: "idle_prompt"})
+
+	if !q.IsIdle(w0) {
+		t.Error("window should be idle")
+	}
+	if d := f.Detached(); len(d) != 1 || d[0] != "/dev/ttys002" {
+		t.Errorf("expected only ttys002 detached, got %v", d)
+	}
+}
+
+func TestHandleIdle_SubmitsInitialPrompt(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+	q.MarkBusy(w0)
+	f.SetWindowOption(w1, hook.InitialPromptKey, "run the tests")
+
+	// First idle: Claude Code is ready, so the prompt goes in instead of queuing
+	h := hook.New(f, q, sw)
+	if err := h.HandleIdle(w1, hook.Payload{}); err != nil {
+		t.Fatalf("HandleIdle: %v", err)
+	}
+	// Typed literally (so a prompt such as "Enter" is not a key name), then
+	// submitted with Enter as a key of its own
+	want := [][]string{
+		{"send-keys", "-t", w1, "-l", "run the tests"},
+		{"send-keys", "-t", w1, "Enter"},
+	}
+	if got := f.SentKeys(w1); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected initial prompt submitted, got %q", got)
+	}
+	if q.IsIdle(w1) {
+		t.Error("expected the window not queued while its prompt starts")
+	}
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Errorf("expected no switch, got active=%s", active)
+	}
+
+	// Later idles queue as usual
+	if err := h.HandleIdle(w1, hook.Payload{}); err != nil {
+		t.Fatalf("HandleIdle: %v", err)
+	}
+	if len(f.SentKeys(w1)) != len(want) {
+		t.Error("expected the initial prompt submitted only once")
+	}
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected switch to %s, got active=%s", w1, active)
+	}
+}
+
 func TestHandleRemove_ClearsWindowOptions(t *testing.T) {
-	tm, q, sw, cleanup := setup(t, "ccq-test-remove")
-	defer cleanup()
+	f, q, sw := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	windowID := windows[0].ID
 
-	h := hook.New(tm, q, sw)
+	h := hook.New(f, q, sw)
 
 	q.MarkIdle(windowID)
-	h.HandleRemove(windowID)
+	h.HandleRemove(windowID, hook.Payload{})
 
-	state, _ := tm.GetWindowOption(windowID, "@ccq_state")
+	state, _ := f.GetWindowOption(windowID, "@ccq_state")
 	if state != "" {
 		t.Errorf("expected @ccq_state to be cleared, got %q", state)
 	}
 }
+
+func TestHandleIdle_RecordsSubstate(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+
+	h := hook.New(f, q, sw)
+	h.HandleIdle(w0, hook.Payload{HookEventName: "Stop"})
+	if got := q.Substate(w0); got != queue.SubstateDone {
+		t.Errorf("expected substate %q, got %q", queue.SubstateDone, got)
+	}
+
+	// idle_prompt keeps the existing sub-state
+	h.HandleIdle(w0, hook.Payload{HookEventName: "Notification", NotificationType: "idle_prompt"})
+	if got := q.Substate(w0); got != queue.SubstateDone {
+		t.Errorf("expected substate to stay %q, got %q", queue.SubstateDone, got)
+	}
+
+	// Going busy clears it
+	h.HandlePromptSubmit(w0, hook.Payload{})
+	if got := q.Substate(w0); got != "" {
+		t.Errorf("expected substate to be cleared on busy, got %q", got)
+	}
+}
+
+func TestHandleIdle_RecordsSession(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+
+	h := hook.New(f, q, sw)
+	h.HandleIdle(w0, hook.Payload{
+		HookEventName:  "Stop",
+		SessionID:      "abc-123",
+		TranscriptPath: "/tmp/abc-123.jsonl",
+	})
+
+	if got, _ := f.GetWindowOption(w0, hook.SessionIDKey); got != "abc-123" {
+		t.Errorf("expected session id abc-123, got %q", got)
+	}
+	if got, _ := f.GetWindowOption(w0, hook.TranscriptPathKey); got != "/tmp/abc-123.jsonl" {
+		t.Errorf("expected transcript path, got %q", got)
+	}
+
+	h.HandleRemove(w0, hook.Payload{HookEventName: "SessionEnd"})
+	if got, _ := f.GetWindowOption(w0, hook.SessionIDKey); got != "" {
+		t.Errorf("expected session id to be cleared, got %q", got)
+	}
+}
+
+func TestHandlers_RecordNotificationAndTool(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+
+	h := hook.New(f, q, sw)
+	q.MarkBusy(w0)
+
+	// PreToolUse on a busy window records the tool without changing state
+	h.HandleBusy(w0, hook.Payload{
+		HookEventName: "PreToolUse",
+		ToolName:      "Bash",
+		ToolInput:     []byte(`{"command": "rm -rf build/"}`),
+	})
+	if got, _ := f.GetWindowOption(w0, hook.LastToolKey); got != "Bash(rm -rf build/)" {
+		t.Errorf("expected last tool to be recorded, got %q", got)
+	}
+
+	h.HandleIdle(w0, hook.Payload{
+		HookEventName:    "Notification",
+		NotificationType: "permission_prompt",
+		Message:          "Claude needs your permission to use Bash",
+	})
+	if got, _ := f.GetWindowOption(w0, hook.NotificationKey); got != "Claude needs your permission to use Bash" {
+		t.Errorf("expected notification to be recorded, got %q", got)
+	}
+
+	// Answering the prompt clears the notification
+	h.HandleBusy(w0, hook.Payload{HookEventName: "PreToolUse", ToolName: "Bash"})
+	if got, _ := f.GetWindowOption(w0, hook.NotificationKey); got != "" {
+		t.Errorf("expected notification to be cleared, got %q", got)
+	}
+}
+
+func TestHandlers_Concurrent(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	ids := []string{windows[0].ID}
+	for i := 1; i < 8; i++ {
+		id, _ := f.NewWindow("/tmp")
+		ids = append(ids, id)
+	}
+
+	h := hook.New(f, q, sw)
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			h.HandleIdle(id, hook.Payload{HookEventName: "Stop"})
+			h.HandlePromptSubmit(id, hook.Payload{})
+			h.HandleIdle(id, hook.Payload{HookEventName: "Stop"})
+		}(id)
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if !q.IsIdle(id) {
+			t.Errorf("window %s should end idle", id)
+		}
+	}
+	if _, err := f.ActiveWindowID(); err != nil {
+		t.Errorf("ActiveWindowID: %v", err)
+	}
+}
+
+func TestHandlePromptSubmit_LockHeldRecordsState(t *testing.T) {
+	f, q, sw := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+	q.MarkIdle(w1)
+
+	// Another hook process holds the session lock past the bounded wait
+	l, err := lock.Acquire(f.SessionName(), time.Second)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer l.Release()
+
+	h := hook.New(f, q, sw)
+	start := time.Now()
+	if err := h.HandlePromptSubmit(w0, hook.Payload{}); err != nil {
+		t.Fatalf("HandlePromptSubmit: %v", err)
+	}
+	if waited := time.Since(start); waited > lock.DefaultTimeout+time.Second {
+		t.Errorf("handler waited %v, exceeding the lock timeout", waited)
+	}
+	if q.IsIdle(w0) {
+		t.Error("state should be recorded even without the lock")
+	}
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Errorf("expected no switch without the lock, got active=%s", active)
+	}
+	if got := sw.PendingSwitch(); got != switcher.PendingLocked {
+		t.Errorf("expected pending switch %q, got %q", switcher.PendingLocked, got)
+	}
+	if len(f.Shell()) != 1 {
+		t.Errorf("expected a scheduled reconcile, got %q", f.Shell())
+	}
+}
@@ -0,0 +1,103 @@
+package hook
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/jingikim/ccq/internal/queue"
+)
+
+// Window options recorded from hook payloads.
+const (
+	SessionIDKey      = "@ccq_session_id"
+	TranscriptPathKey = "@ccq_transcript_path"
+	NotificationKey   = "@ccq_notification" // last Notification message
+	LastToolKey       = "@ccq_last_tool"    // last PreToolUse tool, e.g. Bash(make test)
+)
+
+// WindowKeys lists the window options recorded from hook payloads, for
+// reading alongside queue state in a queue.Snapshot.
+var WindowKeys = []string{SessionIDKey, TranscriptPathKey, NotificationKey, LastToolKey}
+
+// Payload is the JSON Claude Code writes to a hook command's stdin.
+// Event-specific fields are empty for events that do not carry them.
+type Payload struct {
+	SessionID      string `json:"session_id"`
+	TranscriptPath string `json:"transcript_path"`
+	Cwd            string `json:"cwd"`
+	HookEventName  string `json:"hook_event_name"`
+
+	// Notification
+	Message          string `json:"message"`
+	NotificationType string `json:"notification_type"`
+
+	// PreToolUse
+	ToolName  string          `json:"tool_name"`
+	ToolInput json.RawMessage `json:"tool_input"`
+
+	// SessionEnd
+	Reason string `json:"reason"`
+}
+
+// ParsePayload decodes a hook payload. Empty input yields an empty payload,
+// so handlers still work when invoked without one (e.g. manually).
+func ParsePayload(r io.Reader) (Payload, error) {
+	var p Payload
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return p, err
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return p, nil
+	}
+	err = json.Unmarshal(data, &p)
+	return p, err
+}
+
+// Substate returns the idle sub-state the event implies.
+// Returns "" when the event does not change the sub-state (idle_prompt merely
+// re-notifies a window that is already idle).
+func (p Payload) Substate() string {
+	if p.HookEventName == "Stop" {
+		return queue.SubstateDone
+	}
+	switch p.NotificationType {
+	case "permission_prompt":
+		return queue.SubstateWaitingPermission
+	case "elicitation_dialog":
+		return queue.SubstateWaitingInput
+	case "idle_prompt":
+		return ""
+	}
+	// Older Claude Code versions omit notification_type; fall back to the message.
+	if p.HookEventName == "Notification" {
+		if strings.Contains(strings.ToLower(p.Message), "permission") {
+			return queue.SubstateWaitingPermission
+		}
+		return queue.SubstateWaitingInput
+	}
+	return ""
+}
+
+// toolInputKeys lists, in order of preference, the tool_input fields that best
+// describe what a tool is about to do.
+var toolInputKeys = []string{"command", "file_path", "notebook_path", "path", "pattern", "url", "query", "description"}
+
+// ToolSummary describes the tool a PreToolUse event is about to run, e.g.
+// "Bash(rm -rf build/)". Returns "" when the payload carries no tool.
+func (p Payload) ToolSummary() string {
+	if p.ToolName == "" {
+		return ""
+	}
+	var input map[string]any
+	if err := json.Unmarshal(p.ToolInput, &input); err != nil {
+		return p.ToolName
+	}
+	for _, key := range toolInputKeys {
+		if v, ok := input[key].(string); ok && v != "" {
+			return p.ToolName + "(" + strings.Join(strings.Fields(v), " ") + ")"
+		}
+	}
+	return p.ToolName
+}
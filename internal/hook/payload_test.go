@@ -0,0 +1,86 @@
+package hook_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/queue"
+)
+
+func TestParsePayload(t *testing.T) {
+	input := `{
+		"session_id": "abc-123",
+		"transcript_path": "/home/u/.claude/projects/x/abc-123.jsonl",
+		"cwd": "/src/api",
+		"hook_event_name": "PreToolUse",
+		"tool_name": "Bash",
+		"tool_input": {"command": "rm -rf build/"}
+	}`
+	p, err := hook.ParsePayload(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ParsePayload: %v", err)
+	}
+	if p.SessionID != "abc-123" || p.Cwd != "/src/api" || p.HookEventName != "PreToolUse" {
+		t.Errorf("unexpected common fields: %+v", p)
+	}
+	if p.ToolName != "Bash" {
+		t.Errorf("expected tool_name Bash, got %q", p.ToolName)
+	}
+	if !strings.Contains(string(p.ToolInput), "rm -rf build/") {
+		t.Errorf("expected raw tool_input, got %s", p.ToolInput)
+	}
+}
+
+func TestParsePayload_EmptyAndInvalid(t *testing.T) {
+	p, err := hook.ParsePayload(strings.NewReader("  \n"))
+	if err != nil {
+		t.Errorf("expected empty input to be accepted, got %v", err)
+	}
+	if p.HookEventName != "" {
+		t.Errorf("expected empty payload, got %+v", p)
+	}
+
+	if _, err := hook.ParsePayload(strings.NewReader("not json")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestPayloadSubstate(t *testing.T) {
+	tests := []struct {
+		p    hook.Payload
+		want string
+	}{
+		{hook.Payload{HookEventName: "Stop"}, queue.SubstateDone},
+		{hook.Payload{HookEventName: "Notification", NotificationType: "permission_prompt"}, queue.SubstateWaitingPermission},
+		{hook.Payload{HookEventName: "Notification", NotificationType: "elicitation_dialog"}, queue.SubstateWaitingInput},
+		{hook.Payload{HookEventName: "Notification", NotificationType: "idle_prompt"}, ""},
+		{hook.Payload{HookEventName: "Notification", Message: "Claude needs your permission to use Bash"}, queue.SubstateWaitingPermission},
+		{hook.Payload{HookEventName: "Notification", Message: "Claude is waiting for your input"}, queue.SubstateWaitingInput},
+		{hook.Payload{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Substate(); got != tt.want {
+			t.Errorf("%+v.Substate() = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPayloadToolSummary(t *testing.T) {
+	tests := []struct {
+		p    hook.Payload
+		want string
+	}{
+		{hook.Payload{ToolName: "Bash", ToolInput: []byte(`{"command": "rm -rf build/"}`)}, "Bash(rm -rf build/)"},
+		{hook.Payload{ToolName: "Edit", ToolInput: []byte(`{"file_path": "/src/main.go", "old_string": "x"}`)}, "Edit(/src/main.go)"},
+		{hook.Payload{ToolName: "Bash", ToolInput: []byte(`{"command": "go test \\\n  ./..."}`)}, "Bash(go test \\ ./...)"},
+		{hook.Payload{ToolName: "TodoWrite", ToolInput: []byte(`{"todos": []}`)}, "TodoWrite"},
+		{hook.Payload{ToolName: "Task"}, "Task"},
+		{hook.Payload{}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.p.ToolSummary(); got != tt.want {
+			t.Errorf("ToolSummary() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package lock provides a cross-process lock that serializes ccq processes
+// making decisions about the same tmux session (e.g. concurrent hooks).
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// DefaultTimeout bounds how long a hook waits for the lock. It stays well
+// under the 5-second hook timeout in hooks.json.
+const DefaultTimeout = 2 * time.Second
+
+// pollInterval is how often a blocked Acquire retries.
+const pollInterval = 10 * time.Millisecond
+
+// ErrTimeout is returned when the lock could not be acquired in time.
+var ErrTimeout = errors.New("timed out waiting for session lock")
+
+// RuntimeDir returns the directory for ccq's runtime files:
+// $XDG_RUNTIME_DIR/ccq, or a per-user directory under the system temp dir.
+func RuntimeDir() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "ccq")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("ccq-%d", os.Getuid()))
+}
+
+// Path returns the lock file path for a session.
+func Path(session string) string {
+	return filepath.Join(RuntimeDir(), session+".lock")
+}
+
+// Lock is a held session lock.
+type Lock struct {
+	f *os.File
+}
+
+// Acquire takes the exclusive lock for a session, waiting up to timeout.
+// The lock is released by Release or when the process exits.
+func Acquire(session string, timeout time.Duration) (*Lock, error) {
+	path := Path(session)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return &Lock{f: f}, nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			f.Close()
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			f.Close()
+			return nil, ErrTimeout
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// Release unlocks and closes the lock file.
+func (l *Lock) Release() {
+	syscall.Flock(int(l.f.Fd()), syscall.LOCK_UN)
+	l.f.Close()
+}
@@ -0,0 +1,56 @@
+package lock_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/lock"
+)
+
+func TestRuntimeDir(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got := lock.RuntimeDir(); got != "/run/user/1000/ccq" {
+		t.Errorf("RuntimeDir = %q", got)
+	}
+	if got := lock.Path("ccq"); got != filepath.Join("/run/user/1000/ccq", "ccq.lock") {
+		t.Errorf("Path = %q", got)
+	}
+}
+
+func TestAcquire_ExclusiveWithTimeout(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", t.TempDir())
+
+	l, err := lock.Acquire("ccq-test", time.Second)
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	// A second holder must give up after the timeout
+	start := time.Now()
+	if _, err := lock.Acquire("ccq-test", 50*time.Millisecond); !errors.Is(err, lock.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if waited := time.Since(start); waited > time.Second {
+		t.Errorf("Acquire waited %v, expected to stop near the timeout", waited)
+	}
+
+	// Other sessions are independent
+	other, err := lock.Acquire("ccq-other", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Acquire other session: %v", err)
+	}
+	other.Release()
+
+	// Waiters get the lock once it is released
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		l.Release()
+	}()
+	l2, err := lock.Acquire("ccq-test", time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	l2.Release()
+}
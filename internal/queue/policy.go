@@ -0,0 +1,186 @@
+package queue
+
+import (
+	"sort"
+)
+
+// Built-in scheduling policy names, as used in config and @ccq_policy.
+const (
+	PolicyFIFO       = "fifo"
+	PolicyLIFO       = "lifo"
+	PolicyRoundRobin = "round-robin"
+	PolicyWeighted   = "weighted"
+)
+
+// DefaultPolicy is used when no policy is configured.
+const DefaultPolicy = PolicyFIFO
+
+// Candidate is an idle window eligible to be served next.
+type Candidate struct {
+	ID        string
+	Dir       string
+	Priority  string
+	IdleSince int64
+}
+
+// Selection is the input handed to a Policy.
+type Selection struct {
+	Candidates []Candidate // idle windows in window-list order
+	ActiveDir  string      // directory of the active window
+	Now        int64       // current Unix timestamp
+}
+
+// Policy decides which idle window to serve next.
+type Policy interface {
+	// Name returns the identifier used in config and @ccq_policy.
+	Name() string
+	// Select returns the ID of the chosen candidate, or "" if there are none.
+	Select(s Selection) string
+}
+
+var policies = map[string]Policy{
+	PolicyFIFO:       fifoPolicy{},
+	PolicyLIFO:       lifoPolicy{},
+	PolicyRoundRobin: roundRobinPolicy{},
+	PolicyWeighted:   weightedPolicy{},
+}
+
+// LookupPolicy returns the built-in policy with the given name.
+func LookupPolicy(name string) (Policy, bool) {
+	p, ok := policies[name]
+	return p, ok
+}
+
+// PolicyNames returns the names of all built-in policies, sorted.
+func PolicyNames() []string {
+	names := make([]string, 0, len(policies))
+	for name := range policies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// topBand returns the candidates in the highest non-empty priority band.
+func topBand(cands []Candidate) []Candidate {
+	best := priorityRank(PriorityLow) + 1
+	for _, c := range cands {
+		if r := priorityRank(c.Priority); r < best {
+			best = r
+		}
+	}
+	var band []Candidate
+	for _, c := range cands {
+		if priorityRank(c.Priority) == best {
+			band = append(band, c)
+		}
+	}
+	return band
+}
+
+// oldest returns the ID of the candidate idle the longest ("" if none).
+func oldest(cands []Candidate) string {
+	var id string
+	var since int64 = 1<<63 - 1
+	for _, c := range cands {
+		if c.IdleSince < since {
+			since = c.IdleSince
+			id = c.ID
+		}
+	}
+	return id
+}
+
+// fifoPolicy serves the window idle the longest within the highest priority band.
+type fifoPolicy struct{}
+
+func (fifoPolicy) Name() string { return PolicyFIFO }
+
+func (fifoPolicy) Select(s Selection) string {
+	return oldest(topBand(s.Candidates))
+}
+
+// lifoPolicy serves the most recently idle window within the highest priority
+// band, so the context you just left is finished first.
+type lifoPolicy struct{}
+
+func (lifoPolicy) Name() string { return PolicyLIFO }
+
+func (lifoPolicy) Select(s Selection) string {
+	var id string
+	var since int64 = -1
+	for _, c := range topBand(s.Candidates) {
+		if c.IdleSince > since {
+			since = c.IdleSince
+			id = c.ID
+		}
+	}
+	return id
+}
+
+// roundRobinPolicy rotates across project directories within the highest
+// priority band: it serves the oldest idle window in the first directory that
+// sorts after the active window's directory, wrapping around.
+type roundRobinPolicy struct{}
+
+func (roundRobinPolicy) Name() string { return PolicyRoundRobin }
+
+func (roundRobinPolicy) Select(s Selection) string {
+	band := topBand(s.Candidates)
+	if len(band) == 0 {
+		return ""
+	}
+
+	byDir := make(map[string][]Candidate)
+	var dirs []string
+	for _, c := range band {
+		if _, ok := byDir[c.Dir]; !ok {
+			dirs = append(dirs, c.Dir)
+		}
+		byDir[c.Dir] = append(byDir[c.Dir], c)
+	}
+	sort.Strings(dirs)
+
+	next := dirs[0]
+	for _, d := range dirs {
+		if d > s.ActiveDir {
+			next = d
+			break
+		}
+	}
+	return oldest(byDir[next])
+}
+
+// weightedPolicy scores every idle window by wait time multiplied by a
+// priority weight, so long-waiting low priority windows are not starved.
+type weightedPolicy struct{}
+
+func (weightedPolicy) Name() string { return PolicyWeighted }
+
+// priorityWeight returns the wait-time multiplier for a priority level.
+func priorityWeight(p string) int64 {
+	switch p {
+	case PriorityHigh:
+		return 4
+	case PriorityLow:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func (weightedPolicy) Select(s Selection) string {
+	var id string
+	var best int64 = -1
+	for _, c := range s.Candidates {
+		wait := s.Now - c.IdleSince + 1
+		if wait < 1 {
+			wait = 1
+		}
+		if score := wait * priorityWeight(c.Priority); score > best {
+			best = score
+			id = c.ID
+		}
+	}
+	return id
+}
@@ -0,0 +1,162 @@
+package queue_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func selectWith(t *testing.T, name string, sel queue.Selection) string {
+	t.Helper()
+	p, ok := queue.LookupPolicy(name)
+	if !ok {
+		t.Fatalf("policy %q not registered", name)
+	}
+	if p.Name() != name {
+		t.Errorf("policy name = %q, want %q", p.Name(), name)
+	}
+	return p.Select(sel)
+}
+
+func TestPolicies_Empty(t *testing.T) {
+	for _, name := range queue.PolicyNames() {
+		if got := selectWith(t, name, queue.Selection{Now: 100}); got != "" {
+			t.Errorf("%s: expected no selection, got %s", name, got)
+		}
+	}
+}
+
+func TestFIFOAndLIFO(t *testing.T) {
+	sel := queue.Selection{
+		Now: 100,
+		Candidates: []queue.Candidate{
+			{ID: "@1", IdleSince: 10},
+			{ID: "@2", IdleSince: 20},
+			{ID: "@3", IdleSince: 30},
+			{ID: "@4", IdleSince: 40, Priority: queue.PriorityLow},
+		},
+	}
+	if got := selectWith(t, queue.PolicyFIFO, sel); got != "@1" {
+		t.Errorf("fifo: expected @1, got %s", got)
+	}
+	if got := selectWith(t, queue.PolicyLIFO, sel); got != "@3" {
+		t.Errorf("lifo: expected @3 (low priority @4 skipped), got %s", got)
+	}
+}
+
+func TestRoundRobin_RotatesDirectories(t *testing.T) {
+	cands := []queue.Candidate{
+		{ID: "@1", Dir: "/src/api", IdleSince: 10},
+		{ID: "@2", Dir: "/src/api", IdleSince: 5},
+		{ID: "@3", Dir: "/src/web", IdleSince: 20},
+		{ID: "@4", Dir: "/src/cli", IdleSince: 30},
+	}
+
+	tests := []struct {
+		activeDir string
+		want      string
+	}{
+		{"/src/api", "@4"}, // api → cli
+		{"/src/cli", "@3"}, // cli → web
+		{"/src/web", "@2"}, // web wraps to api, oldest in api
+		{"", "@2"},
+	}
+	for _, tt := range tests {
+		sel := queue.Selection{Candidates: cands, ActiveDir: tt.activeDir, Now: 100}
+		if got := selectWith(t, queue.PolicyRoundRobin, sel); got != tt.want {
+			t.Errorf("round-robin from %q: expected %s, got %s", tt.activeDir, tt.want, got)
+		}
+	}
+}
+
+func TestWeighted_LongWaitBeatsPriority(t *testing.T) {
+	sel := queue.Selection{
+		Now: 1000,
+		Candidates: []queue.Candidate{
+			{ID: "@1", IdleSince: 0, Priority: queue.PriorityLow},    // 1001 * 1
+			{ID: "@2", IdleSince: 900, Priority: queue.PriorityHigh}, // 101 * 4
+		},
+	}
+	if got := selectWith(t, queue.PolicyWeighted, sel); got != "@1" {
+		t.Errorf("weighted: expected long-waiting @1, got %s", got)
+	}
+
+	sel.Candidates[0].IdleSince = 800 // 201 * 1
+	if got := selectWith(t, queue.PolicyWeighted, sel); got != "@2" {
+		t.Errorf("weighted: expected high priority @2, got %s", got)
+	}
+}
+
+func TestNext_Policies(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-" + t.Name())
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/web")
+	w2, _ := f.NewWindow("/src/api")
+	f.SetPanePath(w0, "/src/api")
+
+	for id, since := range map[string]string{w1: "100", w2: "200"} {
+		f.SetWindowOption(id, queue.StateKey, "idle")
+		f.SetWindowOption(id, queue.IdleSinceKey, since)
+	}
+
+	q := queue.New(f)
+	tests := []struct {
+		policy string
+		want   string
+	}{
+		{queue.PolicyFIFO, w1},
+		{queue.PolicyLIFO, w2},
+		{queue.PolicyRoundRobin, w1}, // active dir /src/api → next dir /src/web
+	}
+	for _, tt := range tests {
+		q.SetPolicy(tt.policy)
+		if got, _ := q.Next(); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.policy, tt.want, got)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-" + t.Name())
+	w1, _ := f.NewWindow("/src/web")
+	w2, _ := f.NewWindow("/src/api")
+	w3, _ := f.NewWindow("/src/web")
+	w4, _ := f.NewWindow("/src/docs")
+
+	for id, since := range map[string]string{w1: "100", w2: "200", w3: "300"} {
+		f.SetWindowOption(id, queue.StateKey, "idle")
+		f.SetWindowOption(id, queue.IdleSinceKey, since)
+	}
+	f.SetWindowOption(w4, queue.StateKey, "busy")
+
+	q := queue.New(f)
+	q.SetPriority(w3, queue.PriorityHigh)
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{queue.PolicyFIFO, []string{w3, w1, w2}},
+		{queue.PolicyLIFO, []string{w3, w2, w1}},
+	}
+	for _, tt := range tests {
+		q.SetPolicy(tt.policy)
+		windows, _ := q.Snapshot()
+		got := q.Order(windows)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.policy, tt.want, got)
+		}
+	}
+
+	// Round-robin moves on from the directory of each pick
+	q.SetPriority(w3, queue.PriorityNormal)
+	q.SetPolicy(queue.PolicyRoundRobin)
+	windows, _ := q.Snapshot()
+	if got, want := q.Order(windows), []string{w2, w1, w3}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("round-robin: expected %v, got %v", want, got)
+	}
+}
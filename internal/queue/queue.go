@@ -1,26 +1,73 @@
-// Package queue manages window state (idle/busy) and finds the oldest idle window.
+// Package queue manages window state (idle/busy) and picks the next idle window
+// to serve using a pluggable scheduling policy.
 package queue
 
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
 const (
-	StateKey     = "@ccq_state"
-	IdleSinceKey = "@ccq_idle_since"
+	StateKey        = "@ccq_state"
+	IdleSinceKey    = "@ccq_idle_since"
+	PriorityKey     = "@ccq_priority"
+	SnoozedUntilKey = "@ccq_snoozed_until"
+	ExcludedKey     = "@ccq_excluded"
+	SubstateKey     = "@ccq_substate"
+
+	// Session options
+	PolicyKey               = "@ccq_policy"
+	PrioritizePermissionKey = "@ccq_prioritize_permission"
+)
+
+// Idle sub-states, recording why a window is idle.
+const (
+	SubstateWaitingPermission = "waiting_permission" // blocked on a tool approval
+	SubstateWaitingInput      = "waiting_input"      // asked a question or waiting for a prompt
+	SubstateDone              = "done"               // finished its task
+)
+
+// Priority levels. Idle windows in a higher band are served before any window
+// in a lower band; FIFO ordering applies within a band.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
 )
 
+// ValidPriority returns true if p is a known priority level.
+func ValidPriority(p string) bool {
+	switch p {
+	case PriorityHigh, PriorityNormal, PriorityLow:
+		return true
+	}
+	return false
+}
+
+// priorityRank orders priority levels (lower rank is served first).
+// Unset or unknown values rank as normal.
+func priorityRank(p string) int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
 // Queue tracks tmux window states using window-level options.
 type Queue struct {
-	tm *tmux.Tmux
+	tm tmux.Backend
 }
 
-// New creates a Queue for the given tmux session.
-func New(tm *tmux.Tmux) *Queue {
+// New creates a Queue for the given tmux backend.
+func New(tm tmux.Backend) *Queue {
 	return &Queue{tm: tm}
 }
 
@@ -38,41 +85,172 @@ func (q *Queue) MarkIdle(windowID string) error {
 	return q.tm.SetWindowOption(windowID, IdleSinceKey, now)
 }
 
-// MarkBusy marks a window as busy and clears the idle timestamp.
+// SetSubstate records why an idle window is idle.
+func (q *Queue) SetSubstate(windowID, substate string) error {
+	return q.tm.SetWindowOption(windowID, SubstateKey, substate)
+}
+
+// Substate returns the idle sub-state of a window, or "" if unknown.
+func (q *Queue) Substate(windowID string) string {
+	val, _ := q.tm.GetWindowOption(windowID, SubstateKey)
+	return val
+}
+
+// MarkBusy marks a window as busy and clears the idle timestamp and sub-state.
+// Any snooze is lifted: it applied to the question the user chose to ignore,
+// which has now been answered.
 func (q *Queue) MarkBusy(windowID string) error {
 	if err := q.tm.SetWindowOption(windowID, StateKey, "busy"); err != nil {
 		return err
 	}
+	if q.SnoozedUntil(windowID) > 0 {
+		q.Unsnooze(windowID)
+	}
+	q.tm.UnsetWindowOption(windowID, SubstateKey)
 	return q.tm.SetWindowOption(windowID, IdleSinceKey, "0")
 }
 
-// OldestIdle returns the window ID that has been idle the longest.
-// Returns "" if no window is idle.
-func (q *Queue) OldestIdle() (string, error) {
-	windows, err := q.tm.ListWindows()
+// Snooze keeps a window out of the queue for the given duration. The idle
+// timestamp is untouched, so the window rejoins with its original ordering.
+func (q *Queue) Snooze(windowID string, d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("snooze duration must be positive, got %s", d)
+	}
+	until := time.Now().Add(d).Unix()
+	return q.tm.SetWindowOption(windowID, SnoozedUntilKey, strconv.FormatInt(until, 10))
+}
+
+// Unsnooze returns a snoozed window to the queue immediately.
+func (q *Queue) Unsnooze(windowID string) error {
+	return q.tm.UnsetWindowOption(windowID, SnoozedUntilKey)
+}
+
+// SnoozedUntil returns the Unix timestamp a window is snoozed until, or 0 if
+// it is not snoozed (expired snoozes also return 0).
+func (q *Queue) SnoozedUntil(windowID string) int64 {
+	val, _ := q.tm.GetWindowOption(windowID, SnoozedUntilKey)
+	return parseSnoozedUntil(val, time.Now().Unix())
+}
+
+// SetPriority sets the queue priority of a window. Normal priority is stored
+// by unsetting the option so windows without one behave the same.
+func (q *Queue) SetPriority(windowID, priority string) error {
+	if !ValidPriority(priority) {
+		return fmt.Errorf("invalid priority %q (want high, normal or low)", priority)
+	}
+	if priority == PriorityNormal {
+		return q.tm.UnsetWindowOption(windowID, PriorityKey)
+	}
+	return q.tm.SetWindowOption(windowID, PriorityKey, priority)
+}
+
+// Priority returns the queue priority of a window, defaulting to normal.
+func (q *Queue) Priority(windowID string) string {
+	p, _ := q.tm.GetWindowOption(windowID, PriorityKey)
+	return parsePriority(p)
+}
+
+// SetExcluded excludes a window from (or returns it to) auto-switching.
+// Excluded windows still track idle/busy state for the dashboard.
+func (q *Queue) SetExcluded(windowID string, excluded bool) error {
+	if !excluded {
+		return q.tm.UnsetWindowOption(windowID, ExcludedKey)
+	}
+	return q.tm.SetWindowOption(windowID, ExcludedKey, "on")
+}
+
+// IsExcluded returns true if the window is excluded from auto-switching.
+func (q *Queue) IsExcluded(windowID string) bool {
+	val, _ := q.tm.GetWindowOption(windowID, ExcludedKey)
+	return val == "on"
+}
+
+// Next returns the idle window to serve next according to the session's
+// scheduling policy. Returns "" if no window is idle.
+func (q *Queue) Next() (string, error) {
+	windows, err := q.Snapshot()
 	if err != nil {
 		return "", err
 	}
+	return q.NextFrom(windows), nil
+}
 
-	var oldestID string
-	var oldestTime int64 = 1<<63 - 1
+// NextFrom is Next for callers that already hold a Snapshot.
+func (q *Queue) NextFrom(windows []Window) string {
+	return q.Policy().Select(q.selection(windows))
+}
 
-	for _, w := range windows {
-		state, _ := q.tm.GetWindowOption(w.ID, StateKey)
-		if state != "idle" {
-			continue
+// Order returns the IDs of all windows eligible for auto-switch in the order
+// the session's policy would serve them: the next target first, then the one
+// it would pick after switching there, and so on.
+func (q *Queue) Order(windows []Window) []string {
+	sel := q.selection(windows)
+	policy := q.Policy()
+	order := []string{}
+	for len(sel.Candidates) > 0 {
+		id := policy.Select(sel)
+		if id == "" {
+			break
 		}
-		sinceStr, _ := q.tm.GetWindowOption(w.ID, IdleSinceKey)
-		since, err := strconv.ParseInt(sinceStr, 10, 64)
-		if err != nil || since <= 0 {
-			continue
+		order = append(order, id)
+		var rest []Candidate
+		for _, c := range sel.Candidates {
+			if c.ID == id {
+				sel.ActiveDir = c.Dir
+			} else {
+				rest = append(rest, c)
+			}
 		}
-		if since < oldestTime {
-			oldestTime = since
-			oldestID = w.ID
+		sel.Candidates = rest
+	}
+	return order
+}
+
+func (q *Queue) selection(windows []Window) Selection {
+	sel := Selection{
+		Candidates: Candidates(windows, q.PrioritizePermission()),
+		Now:        time.Now().Unix(),
+	}
+	for _, w := range windows {
+		if w.Active {
+			sel.ActiveDir = w.Dir
 		}
 	}
-	return oldestID, nil
+	return sel
+}
+
+// SetPrioritizePermission turns promotion of permission-blocked windows on or off.
+func (q *Queue) SetPrioritizePermission(enabled bool) error {
+	val := "off"
+	if enabled {
+		val = "on"
+	}
+	return q.tm.SetSessionOption(PrioritizePermissionKey, val)
+}
+
+// PrioritizePermission returns true if permission-blocked windows are promoted.
+func (q *Queue) PrioritizePermission() bool {
+	val, _ := q.tm.GetSessionOption(PrioritizePermissionKey)
+	return val == "on"
+}
+
+// SetPolicy changes the session's scheduling policy at runtime.
+func (q *Queue) SetPolicy(name string) error {
+	if _, ok := LookupPolicy(name); !ok {
+		return fmt.Errorf("unknown policy %q (available: %s)", name, strings.Join(PolicyNames(), ", "))
+	}
+	return q.tm.SetSessionOption(PolicyKey, name)
+}
+
+// Policy returns the session's scheduling policy, falling back to
+// DefaultPolicy when unset or unknown.
+func (q *Queue) Policy() Policy {
+	name, _ := q.tm.GetSessionOption(PolicyKey)
+	if p, ok := LookupPolicy(name); ok {
+		return p
+	}
+	p, _ := LookupPolicy(DefaultPolicy)
+	return p
 }
 
 // IsIdle returns true if the window is currently marked idle.
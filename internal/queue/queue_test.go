@@ -9,35 +9,38 @@ import (
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
-func TestMarkAndFindOldestIdle(t *testing.T) {
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
-	}
+func setup(t *testing.T) (*tmux.Fake, *queue.Queue) {
+	t.Helper()
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-" + t.Name())
+	return f, queue.New(f)
+}
 
-	tm := tmux.New("ccq-test-queue")
-	if err := tm.NewSession(); err != nil {
-		t.Fatalf("NewSession: %v", err)
-	}
-	defer tm.KillSession()
+// markIdleAt marks a window idle as of a fixed unix time, so tests can order
+// windows without sleeping.
+func markIdleAt(f *tmux.Fake, q *queue.Queue, id, since string) {
+	q.MarkIdle(id)
+	f.SetWindowOption(id, queue.IdleSinceKey, since)
+}
 
-	q := queue.New(tm)
+func TestMarkAndNext(t *testing.T) {
+	f, q := setup(t)
 
 	// Get default window (index 0)
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
 
 	// Add a second window
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	// Mark both idle (w0 first, w1 later)
-	q.MarkIdle(w0)
-	time.Sleep(time.Second) // ensure different unix timestamps
-	q.MarkIdle(w1)
+	markIdleAt(f, q, w0, "100")
+	markIdleAt(f, q, w1, "200")
 
 	// Oldest idle should be w0
-	oldest, err := q.OldestIdle()
+	oldest, err := q.Next()
 	if err != nil {
-		t.Fatalf("OldestIdle: %v", err)
+		t.Fatalf("Next: %v", err)
 	}
 	if oldest != w0 {
 		t.Errorf("expected oldest idle = %s, got %s", w0, oldest)
@@ -47,35 +50,195 @@ func TestMarkAndFindOldestIdle(t *testing.T) {
 	q.MarkBusy(w0)
 
 	// Now oldest idle should be w1
-	oldest, err = q.OldestIdle()
+	oldest, err = q.Next()
 	if err != nil {
-		t.Fatalf("OldestIdle: %v", err)
+		t.Fatalf("Next: %v", err)
 	}
 	if oldest != w1 {
 		t.Errorf("expected oldest idle = %s, got %s", w1, oldest)
 	}
 }
 
-func TestOldestIdle_NoneIdle(t *testing.T) {
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
+func TestNext_NoneIdle(t *testing.T) {
+	f, q := setup(t)
+	windows, _ := f.ListWindows()
+	q.MarkBusy(windows[0].ID)
+
+	oldest, err := q.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
 	}
+	if oldest != "" {
+		t.Errorf("expected no idle window, got %s", oldest)
+	}
+}
+
+func TestNext_HigherPriorityFirst(t *testing.T) {
+	f, q := setup(t)
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+	w2, _ := f.NewWindow("/tmp")
+
+	// w0 idle first (low), w1 next (normal), w2 last (high)
+	markIdleAt(f, q, w0, "100")
+	markIdleAt(f, q, w1, "200")
+	markIdleAt(f, q, w2, "300")
 
-	tm := tmux.New("ccq-test-queue-none")
-	if err := tm.NewSession(); err != nil {
-		t.Fatalf("NewSession: %v", err)
+	if err := q.SetPriority(w0, queue.PriorityLow); err != nil {
+		t.Fatalf("SetPriority: %v", err)
+	}
+	if err := q.SetPriority(w2, queue.PriorityHigh); err != nil {
+		t.Fatalf("SetPriority: %v", err)
 	}
-	defer tm.KillSession()
 
-	q := queue.New(tm)
-	windows, _ := tm.ListWindows()
-	q.MarkBusy(windows[0].ID)
+	// High priority wins despite being idle for the shortest time
+	oldest, _ := q.Next()
+	if oldest != w2 {
+		t.Errorf("expected high priority window %s, got %s", w2, oldest)
+	}
 
-	oldest, err := q.OldestIdle()
-	if err != nil {
-		t.Fatalf("OldestIdle: %v", err)
+	// Normal beats low
+	q.MarkBusy(w2)
+	oldest, _ = q.Next()
+	if oldest != w1 {
+		t.Errorf("expected normal priority window %s, got %s", w1, oldest)
+	}
+
+	// FIFO within a band once priorities match
+	q.SetPriority(w0, queue.PriorityNormal)
+	oldest, _ = q.Next()
+	if oldest != w0 {
+		t.Errorf("expected FIFO within band (%s), got %s", w0, oldest)
+	}
+	if p := q.Priority(w0); p != queue.PriorityNormal {
+		t.Errorf("expected normal priority after reset, got %q", p)
 	}
+}
+
+func TestSetPriority_Invalid(t *testing.T) {
+	f, q := setup(t)
+	windows, _ := f.ListWindows()
+	if err := q.SetPriority(windows[0].ID, "urgent"); err == nil {
+		t.Error("expected error for invalid priority")
+	}
+}
+
+func TestNext_UsesSessionPolicy(t *testing.T) {
+	f, q := setup(t)
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	markIdleAt(f, q, w0, "100")
+	markIdleAt(f, q, w1, "200")
+
+	// Default policy is FIFO
+	if name := q.Policy().Name(); name != queue.DefaultPolicy {
+		t.Errorf("expected default policy %s, got %s", queue.DefaultPolicy, name)
+	}
+	next, _ := q.Next()
+	if next != w0 {
+		t.Errorf("fifo: expected %s, got %s", w0, next)
+	}
+
+	if err := q.SetPolicy(queue.PolicyLIFO); err != nil {
+		t.Fatalf("SetPolicy: %v", err)
+	}
+	next, _ = q.Next()
+	if next != w1 {
+		t.Errorf("lifo: expected %s, got %s", w1, next)
+	}
+
+	if err := q.SetPolicy("random"); err == nil {
+		t.Error("expected error for unknown policy")
+	}
+}
+
+func TestSnooze_SkipsUntilExpiry(t *testing.T) {
+	f, q := setup(t)
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	markIdleAt(f, q, w0, "100")
+	markIdleAt(f, q, w1, "200")
+
+	if err := q.Snooze(w0, 20*time.Minute); err != nil {
+		t.Fatalf("Snooze: %v", err)
+	}
+	if q.SnoozedUntil(w0) == 0 {
+		t.Error("expected w0 to be snoozed")
+	}
+	oldest, _ := q.Next()
+	if oldest != w1 {
+		t.Errorf("expected snoozed w0 to be skipped, got %s", oldest)
+	}
+
+	// Expired snooze: w0 rejoins ahead of w1 with its original timestamp
+	f.SetWindowOption(w0, queue.SnoozedUntilKey, "1")
+	oldest, _ = q.Next()
+	if oldest != w0 {
+		t.Errorf("expected w0 after snooze expiry, got %s", oldest)
+	}
+
+	// Going busy lifts the snooze
+	q.Snooze(w1, time.Hour)
+	q.MarkBusy(w1)
+	if q.SnoozedUntil(w1) != 0 {
+		t.Error("expected MarkBusy to clear snooze")
+	}
+
+	if err := q.Snooze(w0, 0); err == nil {
+		t.Error("expected error for non-positive duration")
+	}
+}
+
+func TestExcluded_SkippedButStillTracked(t *testing.T) {
+	f, q := setup(t)
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+
+	q.MarkIdle(w0)
+	if err := q.SetExcluded(w0, true); err != nil {
+		t.Fatalf("SetExcluded: %v", err)
+	}
+
+	if !q.IsIdle(w0) {
+		t.Error("excluded window should still track idle state")
+	}
+	oldest, _ := q.Next()
 	if oldest != "" {
-		t.Errorf("expected no idle window, got %s", oldest)
+		t.Errorf("expected excluded window to be skipped, got %s", oldest)
+	}
+
+	q.SetExcluded(w0, false)
+	oldest, _ = q.Next()
+	if oldest != w0 {
+		t.Errorf("expected included window %s, got %s", w0, oldest)
+	}
+}
+
+func TestPrioritizePermission(t *testing.T) {
+	f, q := setup(t)
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	markIdleAt(f, q, w0, "100")
+	q.SetSubstate(w0, queue.SubstateDone)
+	markIdleAt(f, q, w1, "200")
+	q.SetSubstate(w1, queue.SubstateWaitingPermission)
+
+	// Off by default: plain FIFO
+	next, _ := q.Next()
+	if next != w0 {
+		t.Errorf("expected FIFO %s without prioritization, got %s", w0, next)
+	}
+
+	q.SetPrioritizePermission(true)
+	next, _ = q.Next()
+	if next != w1 {
+		t.Errorf("expected permission-blocked %s first, got %s", w1, next)
 	}
 }
@@ -0,0 +1,94 @@
+package queue
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// Window is a window's queue state as read by Snapshot.
+type Window struct {
+	tmux.WindowInfo
+	State        string // "idle", "busy" or "" if untracked
+	Substate     string
+	Priority     string // never empty; unset reads as normal
+	IdleSince    int64  // 0 unless idle
+	SnoozedUntil int64  // 0 unless snoozed (expired snoozes read as 0)
+	Excluded     bool
+}
+
+// IsIdle returns true if the window is marked idle.
+func (w Window) IsIdle() bool {
+	return w.State == "idle"
+}
+
+// windowKeys are the window options every Snapshot reads.
+var windowKeys = []string{StateKey, IdleSinceKey, SubstateKey, PriorityKey, SnoozedUntilKey, ExcludedKey}
+
+// Snapshot reads the queue state of every window in a single tmux call.
+// extra lists additional window options to read into Window.Option, such as
+// details recorded by hooks that the dashboard displays.
+func (q *Queue) Snapshot(extra ...string) ([]Window, error) {
+	keys := append(append([]string(nil), windowKeys...), extra...)
+	infos, err := q.tm.ListWindows(keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	windows := make([]Window, 0, len(infos))
+	for _, info := range infos {
+		w := Window{
+			WindowInfo:   info,
+			State:        info.Option[StateKey],
+			Substate:     info.Option[SubstateKey],
+			Priority:     parsePriority(info.Option[PriorityKey]),
+			SnoozedUntil: parseSnoozedUntil(info.Option[SnoozedUntilKey], now),
+			Excluded:     info.Option[ExcludedKey] == "on",
+		}
+		if w.IsIdle() {
+			w.IdleSince, _ = strconv.ParseInt(info.Option[IdleSinceKey], 10, 64)
+		}
+		windows = append(windows, w)
+	}
+	return windows, nil
+}
+
+// parsePriority normalizes a stored priority, defaulting to normal.
+func parsePriority(val string) string {
+	if !ValidPriority(val) {
+		return PriorityNormal
+	}
+	return val
+}
+
+// parseSnoozedUntil returns the stored snooze deadline, or 0 if unset or
+// already past.
+func parseSnoozedUntil(val string, now int64) int64 {
+	until, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || until <= now {
+		return 0
+	}
+	return until
+}
+
+// Candidates returns the windows eligible for selection: idle, neither
+// snoozed nor excluded, in window-list order.
+//
+// When promotePermission is true, windows blocked on a tool approval are
+// promoted to the high priority band since they are stalling work.
+func Candidates(windows []Window, promotePermission bool) []Candidate {
+	var cands []Candidate
+	for _, w := range windows {
+		if !w.IsIdle() || w.IdleSince <= 0 || w.SnoozedUntil > 0 || w.Excluded {
+			continue
+		}
+		c := Candidate{ID: w.ID, Dir: w.Dir, Priority: w.Priority, IdleSince: w.IdleSince}
+		if promotePermission && w.Substate == SubstateWaitingPermission {
+			c.Priority = PriorityHigh
+		}
+		cands = append(cands, c)
+	}
+	return cands
+}
@@ -0,0 +1,158 @@
+package status
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// GitStatusKey is the session option turning the per-window git status off
+// ("off"; unset means on).
+const GitStatusKey = "@ccq_git_status"
+
+// GitKey is the window option caching the git status of the window's
+// directory: "<unix time> <ahead> <behind> <dirty> <branch> <dir>", with "-"
+// as the branch outside a repository. Caching it on the window lets every
+// status refresh, including `ccq _status` processes, reuse it for GitTTL.
+const GitKey = "@ccq_git"
+
+// GitPendingKey is the session option holding when `ccq _git` was last
+// scheduled (unix time) and has not finished, so renders schedule at most one
+// background git status refresh per GitTTL.
+const GitPendingKey = "@ccq_git_pending"
+
+// GitTTL is how long a cached git status is shown before it is read again.
+const GitTTL = 10 * time.Second
+
+// gitCache is a decoded GitKey value.
+type gitCache struct {
+	at     int64
+	dir    string
+	status *git.Status // nil outside a repository
+}
+
+func parseGitCache(val string) (gitCache, bool) {
+	parts := strings.SplitN(val, " ", 6)
+	if len(parts) != 6 {
+		return gitCache{}, false
+	}
+	at, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return gitCache{}, false
+	}
+	c := gitCache{at: at, dir: parts[5]}
+	if parts[4] != "-" {
+		ahead, _ := strconv.Atoi(parts[1])
+		behind, _ := strconv.Atoi(parts[2])
+		c.status = &git.Status{Branch: parts[4], Dirty: parts[3] == "1", Ahead: ahead, Behind: behind}
+	}
+	return c, true
+}
+
+func (c gitCache) String() string {
+	if c.status == nil {
+		return fmt.Sprintf("%d 0 0 0 - %s", c.at, c.dir)
+	}
+	dirty := 0
+	if c.status.Dirty {
+		dirty = 1
+	}
+	return fmt.Sprintf("%d %d %d %d %s %s", c.at, c.status.Ahead, c.status.Behind, dirty, c.status.Branch, c.dir)
+}
+
+// windowGit returns the cached git status of a snapshot window, or nil if it
+// is not in a repository or has not been read for its current directory.
+func windowGit(w queue.Window) *git.Status {
+	c, ok := parseGitCache(w.Option[GitKey])
+	if !ok || c.dir != w.Dir {
+		return nil
+	}
+	return c.status
+}
+
+// gitFresh reports whether a snapshot window needs no git status read: its
+// cache is for its directory and younger than GitTTL, or its directory can't
+// be stored in an option.
+func gitFresh(w queue.Window, now time.Time) bool {
+	if strings.ContainsAny(w.Dir, "\t\n") {
+		return true
+	}
+	c, ok := parseGitCache(w.Option[GitKey])
+	return ok && c.dir == w.Dir && now.Sub(time.Unix(c.at, 0)) < GitTTL
+}
+
+// GitDue reports whether RefreshGit has any window of a snapshot to read,
+// without running git.
+func GitDue(snapshot []queue.Window) bool {
+	now := time.Now()
+	for _, w := range snapshot {
+		if !gitFresh(w, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduleGit starts a background `ccq _git` (run-shell -b) if the git status
+// of any window in a snapshot is due, so renderers show the cached status
+// without running git themselves. With the git status turned off, stale
+// caches are cleared instead.
+func ScheduleGit(tm tmux.Backend, snapshot []queue.Window) {
+	if !GitDue(snapshot) {
+		return
+	}
+	if v, _ := tm.GetSessionOption(GitStatusKey); v == "off" {
+		RefreshGit(tm, snapshot)
+		return
+	}
+	now := time.Now()
+	if v, _ := tm.GetSessionOption(GitPendingKey); v != "" {
+		at, err := strconv.ParseInt(v, 10, 64)
+		if err == nil && now.Sub(time.Unix(at, 0)) < GitTTL {
+			return
+		}
+	}
+	tm.SetSessionOption(GitPendingKey, strconv.FormatInt(now.Unix(), 10))
+	tm.RunShell("ccq _git")
+}
+
+// RefreshGit reads the git status of the windows in a snapshot (taken with
+// WindowKeys) whose cached one is missing, older than GitTTL or for another
+// directory, and caches it on the window. The snapshot is updated in place.
+// With the git status turned off, stale caches are cleared instead.
+func RefreshGit(tm tmux.Backend, snapshot []queue.Window) {
+	now := time.Now()
+	// Read lazily, so refreshes with fresh caches need no tmux call
+	var checked, enabled bool
+	for _, w := range snapshot {
+		if gitFresh(w, now) {
+			continue
+		}
+		if !checked {
+			v, _ := tm.GetSessionOption(GitStatusKey)
+			checked, enabled = true, v != "off"
+		}
+		if !enabled {
+			if w.Option[GitKey] != "" {
+				tm.UnsetWindowOption(w.ID, GitKey)
+				w.Option[GitKey] = ""
+			}
+			continue
+		}
+
+		c := gitCache{at: now.Unix(), dir: w.Dir}
+		if w.Dir != "" {
+			if s, err := git.ReadStatus(w.Dir); err == nil && s.Branch != "" {
+				c.status = &s
+			}
+		}
+		val := c.String()
+		tm.SetWindowOption(w.ID, GitKey, val)
+		w.Option[GitKey] = val
+	}
+}
@@ -0,0 +1,107 @@
+package status_test
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestRefreshGit(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	if out, err := exec.Command("git", "-C", repo, "init", "-q", "-b", "main").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+
+	f := tmux.NewFake("ccq-fake-git")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	f.SetPanePath(w0, repo)
+	w1, _ := f.NewWindow(t.TempDir())
+
+	q := queue.New(f)
+	snapshot, _ := q.Snapshot(status.WindowKeys...)
+	status.RefreshGit(f, snapshot)
+	got := status.Windows(snapshot)
+	if got[0].Git == nil || *got[0].Git != (git.Status{Branch: "main"}) {
+		t.Errorf("expected main in %s, got %+v", w0, got[0].Git)
+	}
+	if got[1].Git != nil {
+		t.Errorf("expected no git status outside a repository, got %+v", got[1].Git)
+	}
+
+	// Cached on the window, so the next snapshot reuses it without git
+	cached, _ := f.GetWindowOption(w0, status.GitKey)
+	if !strings.HasSuffix(cached, " main "+repo) {
+		t.Errorf("unexpected cache %q", cached)
+	}
+	snapshot, _ = q.Snapshot(status.WindowKeys...)
+	before := f.Calls("GetSessionOption")
+	status.RefreshGit(f, snapshot)
+	if f.Calls("GetSessionOption") != before {
+		t.Error("expected fresh caches to need no tmux calls")
+	}
+	if again, _ := f.GetWindowOption(w0, status.GitKey); again != cached {
+		t.Errorf("expected cache kept, got %q", again)
+	}
+
+	// A cache for another directory is not shown
+	f.SetPanePath(w0, t.TempDir())
+	snapshot, _ = q.Snapshot(status.WindowKeys...)
+	if w := status.Windows(snapshot)[0]; w.Git != nil {
+		t.Errorf("expected stale cache ignored after cd, got %+v", w.Git)
+	}
+
+	// Turned off, caches are cleared as they expire
+	f.SetSessionOption(status.GitStatusKey, "off")
+	status.RefreshGit(f, snapshot)
+	if v, _ := f.GetWindowOption(w0, status.GitKey); v != "" {
+		t.Errorf("expected cache cleared, got %q", v)
+	}
+	if v, _ := f.GetWindowOption(w1, status.GitKey); v == "" {
+		t.Error("expected fresh cache of the unchanged window kept")
+	}
+}
+
+func TestScheduleGit(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-git-schedule")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	q := queue.New(f)
+
+	snapshot, _ := q.Snapshot(status.WindowKeys...)
+	status.ScheduleGit(f, snapshot)
+	status.ScheduleGit(f, snapshot)
+	if shell := f.Shell(); len(shell) != 1 || shell[0] != "ccq _git" {
+		t.Errorf("expected one background refresh while one is pending, got %q", shell)
+	}
+
+	// Fresh caches need nothing scheduled
+	f.UnsetSessionOption(status.GitPendingKey)
+	f.SetWindowOption(w0, status.GitKey, fmt.Sprintf("%d 0 0 0 - %s", time.Now().Unix(), snapshot[0].Dir))
+	snapshot, _ = q.Snapshot(status.WindowKeys...)
+	status.ScheduleGit(f, snapshot)
+	if n := len(f.Shell()); n != 1 {
+		t.Errorf("expected nothing scheduled for fresh caches, got %d runs", n)
+	}
+
+	// Turned off, stale caches are cleared in place of a refresh
+	f.SetSessionOption(status.GitStatusKey, "off")
+	f.SetWindowOption(w0, status.GitKey, "1 0 0 0 - "+snapshot[0].Dir)
+	snapshot, _ = q.Snapshot(status.WindowKeys...)
+	status.ScheduleGit(f, snapshot)
+	if v, _ := f.GetWindowOption(w0, status.GitKey); v != "" || len(f.Shell()) != 1 {
+		t.Errorf("expected cache cleared without a refresh, got %q and %q", v, f.Shell())
+	}
+}
@@ -0,0 +1,190 @@
+// Package status is the read model of a ccq session shared by every output:
+// the dashboard line, `ccq status` (table, --json and --format) and the HTTP
+// API. The JSON field names are a stable schema for scripts and tools.
+package status
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jingikim/ccq/internal/git"
+	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// ProfileKey is the window option naming the config profile a window was
+// launched with (`ccq new --profile`).
+const ProfileKey = "@ccq_profile"
+
+// WorktreeKey and WorktreeBranchKey are the window options recording the git
+// worktree a window was opened in (`ccq new --worktree`) and its branch.
+const (
+	WorktreeKey       = "@ccq_worktree"
+	WorktreeBranchKey = "@ccq_worktree_branch"
+)
+
+// WindowKeys lists the user options a queue snapshot must read for Windows.
+var WindowKeys = append([]string{ProfileKey, WorktreeKey, WorktreeBranchKey, GitKey}, hook.WindowKeys...)
+
+// Window is a tmux window in the ccq session.
+type Window struct {
+	ID             string      `json:"id"`
+	Index          string      `json:"index"`
+	Name           string      `json:"name"`
+	Named          bool        `json:"named,omitempty"` // named explicitly rather than after its command
+	Dir            string      `json:"dir"`
+	Git            *git.Status `json:"git,omitempty"` // nil outside a repository
+	Active         bool        `json:"active"`
+	State          string      `json:"state"` // "idle", "busy", or "" when not tracked yet
+	Substate       string      `json:"substate,omitempty"`
+	Priority       string      `json:"priority"`
+	IdleSince      int64       `json:"idle_since,omitempty"`    // Unix time
+	SnoozedUntil   int64       `json:"snoozed_until,omitempty"` // Unix time
+	Excluded       bool        `json:"excluded"`
+	Profile        string      `json:"profile,omitempty"`  // config profile it was launched with
+	Worktree       string      `json:"worktree,omitempty"` // git worktree it was opened in
+	WorktreeBranch string      `json:"worktree_branch,omitempty"`
+	Notification   string      `json:"notification,omitempty"`
+	LastTool       string      `json:"last_tool,omitempty"`
+	SessionID      string      `json:"session_id,omitempty"` // Claude Code session
+	TranscriptPath string      `json:"transcript_path,omitempty"`
+}
+
+// IsIdle reports whether the window is waiting for the user.
+func (w Window) IsIdle() bool {
+	return w.State == "idle"
+}
+
+// Icon returns the dashboard icon for the window: ▶ active, ◌ snoozed,
+// ◆ waiting on a permission prompt, ◇ waiting for input, ○ idle (done or
+// unknown), ● busy, · not tracked yet.
+func (w Window) Icon() string {
+	if w.Active {
+		return "▶"
+	}
+	switch w.State {
+	case "idle":
+		if w.SnoozedUntil > 0 {
+			return "◌"
+		}
+		switch w.Substate {
+		case queue.SubstateWaitingPermission:
+			return "◆"
+		case queue.SubstateWaitingInput:
+			return "◇"
+		}
+		return "○"
+	case "busy":
+		return "●"
+	}
+	return "·"
+}
+
+// PriorityMark returns a compact marker for non-normal priorities: ↑ high,
+// ↓ low.
+func (w Window) PriorityMark() string {
+	switch w.Priority {
+	case queue.PriorityHigh:
+		return "↑"
+	case queue.PriorityLow:
+		return "↓"
+	}
+	return ""
+}
+
+// ExcludedMark returns ⊘ for windows excluded from auto-switching.
+func (w Window) ExcludedMark() string {
+	if w.Excluded {
+		return "⊘"
+	}
+	return ""
+}
+
+// Session is the ccq session with all its windows.
+type Session struct {
+	Name          string   `json:"session"`
+	AutoSwitch    bool     `json:"auto_switch"`
+	Policy        string   `json:"policy"`
+	PendingSwitch string   `json:"pending_switch,omitempty"`
+	Clients       []string `json:"clients"` // TTYs of attached clients
+	Queue         []string `json:"queue"`   // window IDs in the order auto-switch would serve them
+	Windows       []Window `json:"windows"`
+}
+
+// Load reads the session settings, attached clients and all windows. Git
+// statuses are shown as cached; due ones are refreshed in the background
+// (see ScheduleGit).
+func Load(tm tmux.Backend) (Session, error) {
+	windows, err := queue.New(tm).Snapshot(WindowKeys...)
+	if err != nil {
+		return Session{}, err
+	}
+	ScheduleGit(tm, windows)
+	return FromSnapshot(tm, windows), nil
+}
+
+// FromSnapshot is Load for callers that already hold a snapshot taken with
+// WindowKeys, such as the daemon. It reads only the session settings and
+// clients, never running git.
+func FromSnapshot(tm tmux.Backend, snapshot []queue.Window) Session {
+	q := queue.New(tm)
+	sw := switcher.New(tm, q)
+	clients := tm.ListClients()
+	if clients == nil {
+		clients = []string{}
+	}
+	return Session{
+		Name:          tm.SessionName(),
+		AutoSwitch:    sw.IsAutoSwitchOn(),
+		Policy:        q.Policy().Name(),
+		PendingSwitch: sw.PendingSwitch(),
+		Clients:       clients,
+		Queue:         q.Order(snapshot),
+		Windows:       Windows(snapshot),
+	}
+}
+
+// Windows converts a queue snapshot taken with WindowKeys, for callers that
+// need only the windows (e.g. the dashboard line, which runs every status
+// refresh). Git statuses come from the windows' caches (see RefreshGit).
+func Windows(snapshot []queue.Window) []Window {
+	windows := make([]Window, 0, len(snapshot))
+	for _, w := range snapshot {
+		windows = append(windows, Window{
+			ID:             w.ID,
+			Index:          w.Index,
+			Name:           w.Name,
+			Named:          w.Named,
+			Dir:            w.Dir,
+			Git:            windowGit(w),
+			Active:         w.Active,
+			State:          w.State,
+			Substate:       w.Substate,
+			Priority:       w.Priority,
+			IdleSince:      w.IdleSince,
+			SnoozedUntil:   w.SnoozedUntil,
+			Excluded:       w.Excluded,
+			Profile:        w.Option[ProfileKey],
+			Worktree:       w.Option[WorktreeKey],
+			WorktreeBranch: w.Option[WorktreeBranchKey],
+			Notification:   w.Option[hook.NotificationKey],
+			LastTool:       w.Option[hook.LastToolKey],
+			SessionID:      w.Option[hook.SessionIDKey],
+			TranscriptPath: w.Option[hook.TranscriptPathKey],
+		})
+	}
+	return windows
+}
+
+// FormatDuration renders d compactly in its largest whole unit: 30s, 5m, 2h.
+func FormatDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh", int(d.Hours()))
+}
@@ -0,0 +1,107 @@
+package status_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/hook"
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-status")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+	f.AttachClient("/dev/pts/1")
+
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+	q.SetSubstate(w1, queue.SubstateWaitingPermission)
+	q.SetPriority(w1, queue.PriorityHigh)
+	q.SetPolicy("lifo")
+	f.SetWindowOption(w1, hook.LastToolKey, "Bash(make)")
+	f.SetWindowOption(w1, hook.SessionIDKey, "abc-123")
+	switcher.New(f, q).SetAutoSwitch(true)
+
+	sess, err := status.Load(f)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if sess.Name != "ccq-fake-status" || !sess.AutoSwitch || sess.Policy != "lifo" {
+		t.Errorf("unexpected session: %+v", sess)
+	}
+	if len(sess.Clients) != 1 || sess.Clients[0] != "/dev/pts/1" {
+		t.Errorf("unexpected clients: %v", sess.Clients)
+	}
+	if len(sess.Windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(sess.Windows))
+	}
+	if w := sess.Windows[0]; !w.Active || w.State != "busy" || w.IsIdle() {
+		t.Errorf("unexpected w0: %+v", w)
+	}
+	w := sess.Windows[1]
+	if w.ID != w1 || w.Dir != "/src/api" || !w.IsIdle() || w.IdleSince == 0 {
+		t.Errorf("unexpected w1: %+v", w)
+	}
+	if w.Substate != queue.SubstateWaitingPermission || w.Priority != queue.PriorityHigh {
+		t.Errorf("unexpected w1 queue fields: %+v", w)
+	}
+	if w.LastTool != "Bash(make)" || w.SessionID != "abc-123" {
+		t.Errorf("unexpected w1 hook fields: %+v", w)
+	}
+}
+
+func TestLoad_NoClients(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-status-empty")
+
+	sess, err := status.Load(f)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	// Encoded as [] rather than null for scripts
+	if sess.Clients == nil {
+		t.Error("expected empty, non-nil clients")
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{30 * time.Second, "30s"},
+		{90 * time.Second, "1m"},
+		{5 * time.Minute, "5m"},
+		{2 * time.Hour, "2h"},
+	}
+	for _, tt := range tests {
+		got := status.FormatDuration(tt.d)
+		if got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestWindowMarks(t *testing.T) {
+	tests := []struct {
+		w    status.Window
+		want string
+	}{
+		{status.Window{Priority: queue.PriorityNormal}, ""},
+		{status.Window{Priority: queue.PriorityHigh}, "↑"},
+		{status.Window{Priority: queue.PriorityLow, Excluded: true}, "↓⊘"},
+		{status.Window{Excluded: true}, "⊘"},
+	}
+	for _, tt := range tests {
+		if got := tt.w.PriorityMark() + tt.w.ExcludedMark(); got != tt.want {
+			t.Errorf("marks for %+v = %q, want %q", tt.w, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package switcher
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jingikim/ccq/internal/lock"
+)
+
+// SwitchHistoryKey is the session option holding the switch history (see
+// History).
+const SwitchHistoryKey = "@ccq_switch_history"
+
+// MaxSwitchHistory bounds the number of switches remembered.
+const MaxSwitchHistory = 50
+
+// Reasons recorded with each switch.
+const (
+	ReasonAuto   = "auto"   // auto-switch (hook, reconcile or TrySwitch)
+	ReasonReturn = "return" // back to the previous window after a new window's setup
+	ReasonNew    = "new"    // a window opened by ccq
+	ReasonNext   = "next"   // ccq next
+	ReasonPrev   = "prev"   // ccq prev
+	ReasonUI     = "ui"     // jump from ccq ui
+	ReasonAPI    = "api"    // HTTP API focus request
+)
+
+// Switch is one focus change made by ccq.
+type Switch struct {
+	From   string `json:"from"` // window ID, "" if unknown
+	To     string `json:"to"`
+	Reason string `json:"reason"`
+	At     int64  `json:"at"` // Unix time
+}
+
+// Focus selects a window and records the switch in the history.
+func (s *Switcher) Focus(windowID, reason string) error {
+	from, _ := s.tm.ActiveWindowID()
+	return s.focus(from, windowID, reason)
+}
+
+// focus is Focus for callers that already know the active window.
+func (s *Switcher) focus(from, to, reason string) error {
+	if err := s.tm.SelectWindow(to); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	history := append(s.History(), Switch{From: from, To: to, Reason: reason, At: time.Now().Unix()})
+	return s.setHistory(history)
+}
+
+// History returns the switches ccq made, oldest first. Entries undone by Back
+// are removed.
+func (s *Switcher) History() []Switch {
+	val, _ := s.tm.GetSessionOption(SwitchHistoryKey)
+	var history []Switch
+	for _, entry := range strings.Fields(val) {
+		// at,from,to,reason
+		parts := strings.Split(entry, ",")
+		if len(parts) != 4 {
+			continue
+		}
+		at, _ := strconv.ParseInt(parts[0], 10, 64)
+		history = append(history, Switch{At: at, From: parts[1], To: parts[2], Reason: parts[3]})
+	}
+	return history
+}
+
+func (s *Switcher) setHistory(history []Switch) error {
+	if len(history) == 0 {
+		return s.tm.UnsetSessionOption(SwitchHistoryKey)
+	}
+	if len(history) > MaxSwitchHistory {
+		history = history[len(history)-MaxSwitchHistory:]
+	}
+	entries := make([]string, len(history))
+	for i, sw := range history {
+		entries[i] = fmt.Sprintf("%d,%s,%s,%s", sw.At, sw.From, sw.To, sw.Reason)
+	}
+	return s.tm.SetSessionOption(SwitchHistoryKey, strings.Join(entries, " "))
+}
+
+// Back undoes the latest switch by returning to the window it came from, and
+// drops it from the history so repeated calls walk further back. Switches from
+// windows that have since closed are skipped. Returns the selected window ID,
+// or "" if the history is exhausted.
+func (s *Switcher) Back() (string, error) {
+	l, err := lock.Acquire(s.tm.SessionName(), lock.DefaultTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer l.Release()
+
+	windows, err := s.tm.ListWindows()
+	if err != nil {
+		return "", err
+	}
+	exists := make(map[string]bool, len(windows))
+	var active string
+	for _, w := range windows {
+		exists[w.ID] = true
+		if w.Active {
+			active = w.ID
+		}
+	}
+
+	history := s.History()
+	for len(history) > 0 {
+		last := history[len(history)-1]
+		history = history[:len(history)-1]
+		if !exists[last.From] || last.From == active {
+			continue
+		}
+		if err := s.tm.SelectWindow(last.From); err != nil {
+			return "", err
+		}
+		return last.From, s.setHistory(history)
+	}
+	return "", s.setHistory(nil)
+}
@@ -0,0 +1,99 @@
+package switcher_test
+
+import (
+	"testing"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestHistory_RecordsSwitches(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-history")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+	w2, _ := f.NewWindow("/src/web")
+	f.SelectWindow(w0)
+
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+
+	if !sw.TrySwitch() {
+		t.Fatal("expected auto-switch to w1")
+	}
+	if err := sw.Focus(w2, switcher.ReasonUI); err != nil {
+		t.Fatal(err)
+	}
+	sw.Focus(w2, switcher.ReasonUI) // already there: not recorded
+
+	history := sw.History()
+	if len(history) != 2 {
+		t.Fatalf("expected 2 switches, got %+v", history)
+	}
+	if h := history[0]; h.From != w0 || h.To != w1 || h.Reason != switcher.ReasonAuto || h.At == 0 {
+		t.Errorf("unexpected first switch %+v", h)
+	}
+	if h := history[1]; h.From != w1 || h.To != w2 || h.Reason != switcher.ReasonUI {
+		t.Errorf("unexpected second switch %+v", h)
+	}
+}
+
+func TestBack_WalksHistory(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-back")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+	w2, _ := f.NewWindow("/src/web")
+	w3, _ := f.NewWindow("/src/docs")
+	f.SelectWindow(w0)
+
+	sw := switcher.New(f, queue.New(f))
+	sw.Focus(w1, switcher.ReasonAuto)
+	sw.Focus(w2, switcher.ReasonAuto)
+	sw.Focus(w3, switcher.ReasonAuto)
+	f.KillWindow(w2)
+	f.SelectWindow(w3)
+
+	// w3 came from the closed w2, so Back skips to where w2 came from
+	if got, err := sw.Back(); err != nil || got != w1 {
+		t.Fatalf("Back = %q, %v; want %s", got, err, w1)
+	}
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected %s active, got %s", w1, active)
+	}
+	if got, _ := sw.Back(); got != w0 {
+		t.Errorf("second Back = %q, want %s", got, w0)
+	}
+	if got, _ := sw.Back(); got != "" {
+		t.Errorf("expected exhausted history, got %q", got)
+	}
+	if len(sw.History()) != 0 {
+		t.Errorf("expected history cleared, got %+v", sw.History())
+	}
+}
+
+func TestHistory_Bounded(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-history-bound")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+
+	sw := switcher.New(f, queue.New(f))
+	for i := 0; i < switcher.MaxSwitchHistory+5; i++ {
+		if i%2 == 0 {
+			sw.Focus(w1, switcher.ReasonNext)
+		} else {
+			sw.Focus(w0, switcher.ReasonPrev)
+		}
+	}
+	if n := len(sw.History()); n != switcher.MaxSwitchHistory {
+		t.Errorf("expected history capped at %d, got %d", switcher.MaxSwitchHistory, n)
+	}
+}
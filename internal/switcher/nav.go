@@ -0,0 +1,128 @@
+package switcher
+
+import (
+	"strings"
+
+	"github.com/jingikim/ccq/internal/lock"
+)
+
+// NavHistoryPrefix prefixes the session option holding a client's navigation
+// history (see NavHistoryKey).
+const NavHistoryPrefix = "@ccq_nav_"
+
+// MaxNavHistory bounds the number of windows remembered per client.
+const MaxNavHistory = 20
+
+// NavHistoryKey returns the session option holding the navigation history of
+// the client on the given TTY: the IDs of the windows `ccq next` left,
+// most recent last. "/dev/pts/3" maps to "@ccq_nav_dev_pts_3".
+func NavHistoryKey(client string) string {
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, strings.TrimPrefix(client, "/"))
+	return NavHistoryPrefix + name
+}
+
+// Next moves the client to the window auto-switch would select next (per the
+// scheduling policy, skipping snoozed and excluded windows), whether or not
+// auto-switch is on, and remembers the window it left for Prev. Returns the
+// selected window ID, or "" if no other window is waiting.
+func (s *Switcher) Next(client string) (string, error) {
+	l, err := lock.Acquire(s.tm.SessionName(), lock.DefaultTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer l.Release()
+
+	windows, err := s.q.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	var active, target string
+	for _, w := range windows {
+		if w.Active {
+			active = w.ID
+		}
+	}
+	for _, id := range s.q.Order(windows) {
+		if id != active {
+			target = id
+			break
+		}
+	}
+	if target == "" {
+		return "", nil
+	}
+
+	if err := s.focus(active, target, ReasonNext); err != nil {
+		return "", err
+	}
+	if active != "" {
+		history := s.NavHistory(client)
+		if len(history) == 0 || history[len(history)-1] != active {
+			history = append(history, active)
+		}
+		s.setNavHistory(client, history)
+	}
+	return target, nil
+}
+
+// Prev moves the client back to the window it left with the latest Next,
+// skipping windows that have since closed. Returns the selected window ID, or
+// "" if the history is exhausted.
+func (s *Switcher) Prev(client string) (string, error) {
+	l, err := lock.Acquire(s.tm.SessionName(), lock.DefaultTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer l.Release()
+
+	windows, err := s.q.Snapshot()
+	if err != nil {
+		return "", err
+	}
+	exists := make(map[string]bool, len(windows))
+	var active string
+	for _, w := range windows {
+		exists[w.ID] = true
+		if w.Active {
+			active = w.ID
+		}
+	}
+
+	history := s.NavHistory(client)
+	for len(history) > 0 {
+		id := history[len(history)-1]
+		history = history[:len(history)-1]
+		if !exists[id] || id == active {
+			continue
+		}
+		if err := s.focus(active, id, ReasonPrev); err != nil {
+			return "", err
+		}
+		s.setNavHistory(client, history)
+		return id, nil
+	}
+	s.setNavHistory(client, nil)
+	return "", nil
+}
+
+// NavHistory returns the client's navigation history, most recent last.
+func (s *Switcher) NavHistory(client string) []string {
+	val, _ := s.tm.GetSessionOption(NavHistoryKey(client))
+	return strings.Fields(val)
+}
+
+func (s *Switcher) setNavHistory(client string, history []string) {
+	if len(history) == 0 {
+		s.tm.UnsetSessionOption(NavHistoryKey(client))
+		return
+	}
+	if len(history) > MaxNavHistory {
+		history = history[len(history)-MaxNavHistory:]
+	}
+	s.tm.SetSessionOption(NavHistoryKey(client), strings.Join(history, " "))
+}
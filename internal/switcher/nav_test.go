@@ -0,0 +1,86 @@
+package switcher_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestNavHistoryKey(t *testing.T) {
+	if got := switcher.NavHistoryKey("/dev/pts/3"); got != "@ccq_nav_dev_pts_3" {
+		t.Errorf("NavHistoryKey = %q", got)
+	}
+}
+
+func TestNextAndPrev_ManualMode(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-nav")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+	w2, _ := f.NewWindow("/src/web")
+	w3, _ := f.NewWindow("/src/docs")
+	f.SelectWindow(w0)
+
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+	q.MarkIdle(w2)
+	f.SetWindowOption(w1, queue.IdleSinceKey, "100") // idle the longest
+	q.MarkIdle(w3)
+	q.Snooze(w3, time.Hour)
+
+	sw := switcher.New(f, q) // auto-switch off
+	const client = "/dev/pts/1"
+
+	// Oldest idle first, snoozed windows skipped
+	if got, err := sw.Next(client); err != nil || got != w1 {
+		t.Fatalf("Next = %q, %v; want %s", got, err, w1)
+	}
+	if got, _ := sw.Next(client); got != w2 {
+		t.Fatalf("second Next = %q, want %s", got, w2)
+	}
+	if got := sw.NavHistory(client); !reflect.DeepEqual(got, []string{w0, w1}) {
+		t.Errorf("history = %q, want [%s %s]", got, w0, w1)
+	}
+	if other := sw.NavHistory("/dev/pts/2"); len(other) != 0 {
+		t.Errorf("expected history per client, got %q for another client", other)
+	}
+
+	// Back through the history; closed windows are skipped
+	f.KillWindow(w1)
+	if got, _ := sw.Prev(client); got != w0 {
+		t.Errorf("Prev = %q, want %s", got, w0)
+	}
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Errorf("expected %s active, got %s", w0, active)
+	}
+	if got, _ := sw.Prev(client); got != "" {
+		t.Errorf("expected exhausted history, got %q", got)
+	}
+}
+
+func TestNext_NothingWaiting(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-nav-empty")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+
+	q := queue.New(f)
+	q.MarkIdle(w0) // only the active window is idle
+
+	sw := switcher.New(f, q)
+	if got, err := sw.Next("/dev/pts/1"); err != nil || got != "" {
+		t.Errorf("Next = %q, %v; want no target", got, err)
+	}
+	if f.Calls("SelectWindow") != 0 {
+		t.Error("expected no window selected")
+	}
+	if got := sw.NavHistory("/dev/pts/1"); len(got) != 0 {
+		t.Errorf("expected no history, got %q", got)
+	}
+}
@@ -1,25 +1,60 @@
 // Package switcher implements auto-switch logic that moves the user
-// to the oldest idle tmux window when the current window is busy.
+// to the next idle tmux window (per the queue's scheduling policy) when the
+// current window is busy.
 package switcher
 
 import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jingikim/ccq/internal/lock"
 	"github.com/jingikim/ccq/internal/queue"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
-const autoSwitchKey = "@ccq_auto_switch"
+const (
+	autoSwitchKey    = "@ccq_auto_switch"
+	TypingGraceKey   = "@ccq_typing_grace"   // seconds; "0" disables typing suppression
+	PendingSwitchKey = "@ccq_pending_switch" // why a switch is waiting; unset when none is
+)
+
+// Reasons recorded in PendingSwitchKey when a switch is declined while an idle
+// window is waiting.
+const (
+	PendingActiveIdle = "active_idle" // the active window is idle
+	PendingExcluded   = "excluded"    // the active window is excluded
+	PendingTyping     = "typing"      // the user is typing
+	PendingLocked     = "locked"      // the session lock was not acquired
+)
+
+// LockRetryDelay is how long to wait before re-evaluating a switch that was
+// skipped because another process held the session lock.
+const LockRetryDelay = time.Second
+
+// DefaultTypingGrace is used when @ccq_typing_grace is unset.
+const DefaultTypingGrace = 3 * time.Second
 
 // Switcher manages automatic window switching based on queue state.
 type Switcher struct {
-	tm *tmux.Tmux
+	tm tmux.Backend
 	q  *queue.Queue
+
+	onTick bool // see ReconcileOnTick
 }
 
-// New creates a Switcher for the given tmux session and queue.
-func New(tm *tmux.Tmux, q *queue.Queue) *Switcher {
+// New creates a Switcher for the given tmux backend and queue.
+func New(tm tmux.Backend, q *queue.Queue) *Switcher {
 	return &Switcher{tm: tm, q: q}
 }
 
+// ReconcileOnTick is for owners that call Reconcile every second themselves
+// (the daemon): deferred switches are then only recorded, not also scheduled
+// with `ccq _reconcile`.
+func (s *Switcher) ReconcileOnTick() {
+	s.onTick = true
+}
+
 // SetAutoSwitch enables or disables auto-switch via a session option.
 func (s *Switcher) SetAutoSwitch(enabled bool) error {
 	val := "off"
@@ -35,32 +70,198 @@ func (s *Switcher) IsAutoSwitchOn() bool {
 	return val == "on"
 }
 
-// TrySwitch attempts an auto-switch. Returns true if a switch occurred.
+// SetTypingGrace sets how long after the last client input a hook-triggered
+// switch is deferred. Zero disables typing suppression. Client activity is
+// tracked in whole seconds, so d is rounded up: 500ms still suppresses
+// switches rather than turning suppression off.
+func (s *Switcher) SetTypingGrace(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("typing grace must not be negative, got %s", d)
+	}
+	secs := (d + time.Second - 1) / time.Second
+	return s.tm.SetSessionOption(TypingGraceKey, strconv.Itoa(int(secs)))
+}
+
+// TypingGrace returns the typing grace period, defaulting to DefaultTypingGrace.
+func (s *Switcher) TypingGrace() time.Duration {
+	val, _ := s.tm.GetSessionOption(TypingGraceKey)
+	secs, err := strconv.Atoi(val)
+	if err != nil || secs < 0 {
+		return DefaultTypingGrace
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// TrySwitch attempts an auto-switch on an explicit user request (e.g. turning
+// auto-switch on). Returns true if a switch occurred.
 // Rules:
 // 1. If auto-switch is off, do not switch.
 // 2. If the current window is idle, do not switch (user may be typing).
-// 3. If the current window is busy, switch to the oldest idle window.
+// 3. If the current window is excluded, do not switch (user is checking it manually).
+// 4. If the current window is busy, switch to the next idle window (per policy).
+//
+// The decision is serialized with other ccq processes by the session lock, so
+// concurrent hooks cannot both switch. If the lock is not acquired within
+// lock.DefaultTimeout, the switch is deferred (see DeferSwitch).
 func (s *Switcher) TrySwitch() bool {
+	l, err := lock.Acquire(s.tm.SessionName(), lock.DefaultTimeout)
+	if err != nil {
+		s.DeferSwitch(PendingLocked, LockRetryDelay)
+		return false
+	}
+	defer l.Release()
+	return s.trySwitch("", false)
+}
+
+// TrySwitchLocked attempts an auto-switch in response to a hook fired by the
+// trigger window, for callers already holding the session lock.
+//
+// On top of the TrySwitch rules, a switch triggered by a background window is
+// deferred while the user has touched a client within the typing grace period
+// (they may be reading output or typing a follow-up in the busy window); a
+// re-evaluation is scheduled for when the grace period elapses. Switches
+// triggered by the active window itself (e.g. submitting a prompt there) are
+// never deferred, since that input is what prompted them.
+func (s *Switcher) TrySwitchLocked(trigger string) bool {
+	return s.trySwitch(trigger, true)
+}
+
+// Reconcile re-evaluates a pending switch, applying the same rules as a switch
+// triggered by a background window. It is a no-op when no switch is pending.
+// Run by `ccq _reconcile`, which is scheduled after a deferral and fired by
+// tmux hooks when the user changes window or attaches.
+func (s *Switcher) Reconcile() bool {
+	if s.PendingSwitch() == "" {
+		return false
+	}
+	return s.Retry()
+}
+
+// Retry applies the rules of a switch triggered by a background window, for
+// queue changes that no hook reports (e.g. a snooze running out).
+func (s *Switcher) Retry() bool {
+	l, err := lock.Acquire(s.tm.SessionName(), lock.DefaultTimeout)
+	if err != nil {
+		s.DeferSwitch(PendingLocked, LockRetryDelay)
+		return false
+	}
+	defer l.Release()
+	return s.trySwitch("", true)
+}
+
+// PendingSwitch returns why a switch is waiting, or "" if none is.
+func (s *Switcher) PendingSwitch() string {
+	val, _ := s.tm.GetSessionOption(PendingSwitchKey)
+	return val
+}
+
+// DeferSwitch records a pending switch and schedules a reconcile after d, for
+// callers that could not make the decision now (e.g. the lock was busy).
+func (s *Switcher) DeferSwitch(reason string, d time.Duration) {
+	s.tm.SetSessionOption(PendingSwitchKey, reason)
+	if !s.onTick {
+		s.schedule(d, "ccq _reconcile")
+	}
+}
+
+// RetryAfter schedules a Retry after d, for a queue change that no hook will
+// report when it takes effect (a snooze running out). In the daemon the tick
+// notices it instead.
+func (s *Switcher) RetryAfter(d time.Duration) {
+	if !s.onTick {
+		s.schedule(d, "ccq _reconcile --retry")
+	}
+}
+
+// trySwitch applies the switch rules. Whenever it declines while another idle
+// window is waiting, the reason is recorded in PendingSwitchKey so the switch
+// is retried rather than lost; once the switch happens or nothing is waiting,
+// the pending switch is cleared.
+func (s *Switcher) trySwitch(trigger string, checkTyping bool) bool {
 	if !s.IsAutoSwitchOn() {
+		s.tm.UnsetSessionOption(PendingSwitchKey)
 		return false
 	}
 
-	activeID, err := s.tm.ActiveWindowID()
+	// One snapshot serves both the active-window checks and the queue.
+	windows, err := s.q.Snapshot()
 	if err != nil {
 		return false
 	}
 
-	if s.q.IsIdle(activeID) {
+	var active *queue.Window
+	for i := range windows {
+		if windows[i].Active {
+			active = &windows[i]
+		}
+	}
+	if active == nil {
+		return false
+	}
+	if active.IsIdle() || active.Excluded {
+		// Nothing to schedule: the tmux hooks reconcile once the user leaves
+		// this window, and a hook fires anyway when it goes busy.
+		if waitingBesides(windows, active.ID) {
+			reason := PendingActiveIdle
+			if active.Excluded {
+				reason = PendingExcluded
+			}
+			s.tm.SetSessionOption(PendingSwitchKey, reason)
+		} else {
+			s.tm.UnsetSessionOption(PendingSwitchKey)
+		}
 		return false
 	}
 
-	target, err := s.q.OldestIdle()
-	if err != nil || target == "" {
+	target := s.q.NextFrom(windows)
+	if target == "" {
+		s.tm.UnsetSessionOption(PendingSwitchKey)
 		return false
 	}
 
-	if err := s.tm.SelectWindow(target); err != nil {
+	if checkTyping && trigger != active.ID {
+		if wait := s.typingWait(); wait > 0 {
+			s.DeferSwitch(PendingTyping, wait)
+			return false
+		}
+	}
+
+	// Cleared first so the window-changed hook does not start a reconcile.
+	s.tm.UnsetSessionOption(PendingSwitchKey)
+	if err := s.focus(active.ID, target, ReasonAuto); err != nil {
 		return false
 	}
 	return true
 }
+
+// waitingBesides reports whether an idle window other than activeID is
+// queued for a switch.
+func waitingBesides(windows []queue.Window, activeID string) bool {
+	for _, c := range queue.Candidates(windows, false) {
+		if c.ID != activeID {
+			return true
+		}
+	}
+	return false
+}
+
+// typingWait returns how much of the typing grace period remains since the
+// last client input, or 0 if the user has been inactive long enough.
+func (s *Switcher) typingWait() time.Duration {
+	grace := s.TypingGrace()
+	if grace <= 0 {
+		return 0
+	}
+	last := s.tm.ClientActivity()
+	if last <= 0 {
+		return 0
+	}
+	return time.Until(time.Unix(last, 0).Add(grace))
+}
+
+// schedule runs a ccq command from the tmux server after d, rounded up to
+// whole seconds.
+func (s *Switcher) schedule(d time.Duration, command string) {
+	secs := int((d + time.Second - 1) / time.Second)
+	s.tm.RunShell(fmt.Sprintf("sleep %d; %s", secs, command))
+}
@@ -1,39 +1,34 @@
 package switcher_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jingikim/ccq/internal/queue"
 	"github.com/jingikim/ccq/internal/switcher"
 	"github.com/jingikim/ccq/internal/tmux"
 )
 
-func setup(t *testing.T, name string) (*tmux.Tmux, *queue.Queue, func()) {
+func setup(t *testing.T) (*tmux.Fake, *queue.Queue) {
 	t.Helper()
-	if !tmux.IsInstalled() {
-		t.Skip("tmux not installed")
-	}
-	tm := tmux.New(name)
-	if err := tm.NewSession(); err != nil {
-		t.Fatalf("NewSession: %v", err)
-	}
-	q := queue.New(tm)
-	return tm, q, func() { tm.KillSession() }
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-" + t.Name())
+	return f, queue.New(f)
 }
 
 func TestAutoSwitch_CurrentBusy_SwitchesToOldestIdle(t *testing.T) {
-	tm, q, cleanup := setup(t, "ccq-test-switch-1")
-	defer cleanup()
+	f, q := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	// w0=busy (currently active), w1=idle
 	q.MarkBusy(w0)
 	q.MarkIdle(w1)
 
-	sw := switcher.New(tm, q)
+	sw := switcher.New(f, q)
 	sw.SetAutoSwitch(true)
 	switched := sw.TrySwitch()
 
@@ -41,25 +36,24 @@ func TestAutoSwitch_CurrentBusy_SwitchesToOldestIdle(t *testing.T) {
 		t.Error("expected switch to happen")
 	}
 
-	activeID, _ := tm.ActiveWindowID()
+	activeID, _ := f.ActiveWindowID()
 	if activeID != w1 {
 		t.Errorf("expected active window = %s, got %s", w1, activeID)
 	}
 }
 
 func TestAutoSwitch_CurrentIdle_NoSwitch(t *testing.T) {
-	tm, q, cleanup := setup(t, "ccq-test-switch-2")
-	defer cleanup()
+	f, q := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	// both idle, user might be typing in w0
 	q.MarkIdle(w0)
 	q.MarkIdle(w1)
 
-	sw := switcher.New(tm, q)
+	sw := switcher.New(f, q)
 	sw.SetAutoSwitch(true)
 	switched := sw.TrySwitch()
 
@@ -69,17 +63,16 @@ func TestAutoSwitch_CurrentIdle_NoSwitch(t *testing.T) {
 }
 
 func TestAutoSwitch_Disabled_NoSwitch(t *testing.T) {
-	tm, q, cleanup := setup(t, "ccq-test-switch-3")
-	defer cleanup()
+	f, q := setup(t)
 
-	windows, _ := tm.ListWindows()
+	windows, _ := f.ListWindows()
 	w0 := windows[0].ID
-	w1, _ := tm.NewWindow("/tmp")
+	w1, _ := f.NewWindow("/tmp")
 
 	q.MarkBusy(w0)
 	q.MarkIdle(w1)
 
-	sw := switcher.New(tm, q)
+	sw := switcher.New(f, q)
 	sw.SetAutoSwitch(false) // toggle OFF
 	switched := sw.TrySwitch()
 
@@ -87,3 +80,287 @@ func TestAutoSwitch_Disabled_NoSwitch(t *testing.T) {
 		t.Error("expected no switch when auto-switch is disabled")
 	}
 }
+
+func TestAutoSwitch_CurrentExcluded_NoSwitch(t *testing.T) {
+	f, q := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	// w0 = active plain shell excluded from auto-switching, w1 = idle
+	q.SetExcluded(w0, true)
+	q.MarkIdle(w1)
+
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+	if sw.TrySwitch() {
+		t.Error("expected no switch away from an excluded window")
+	}
+}
+
+func TestAutoSwitch_TargetExcluded_NoSwitch(t *testing.T) {
+	f, q := setup(t)
+
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+	q.SetExcluded(w1, true)
+
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+	if sw.TrySwitch() {
+		t.Error("expected no switch to an excluded window")
+	}
+}
+
+func TestTrySwitch_SingleSnapshot(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-" + t.Name())
+	q := queue.New(f)
+	windows, _ := f.ListWindows()
+	q.MarkBusy(windows[0].ID)
+	for i := 0; i < 15; i++ {
+		id, _ := f.NewWindow("/tmp")
+		q.MarkIdle(id)
+	}
+	listBefore := f.Calls("ListWindows")
+	optBefore := f.Calls("GetWindowOption")
+
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+	if !sw.TrySwitch() {
+		t.Fatal("expected switch to happen")
+	}
+	if n := f.Calls("ListWindows") - listBefore; n != 1 {
+		t.Errorf("expected 1 list-windows call, got %d", n)
+	}
+	if n := f.Calls("GetWindowOption") - optBefore; n != 0 {
+		t.Errorf("expected no per-window option reads, got %d", n)
+	}
+	if n := f.Calls("ActiveWindowID"); n != 0 {
+		t.Errorf("expected active window to come from the snapshot, got %d calls", n)
+	}
+}
+
+// setupTyping builds a fake session where the active window w0 is busy, w1 is
+// idle, and an attached client saw input just now.
+func setupTyping(t *testing.T) (*tmux.Fake, *switcher.Switcher, string, string) {
+	t.Helper()
+	f := tmux.NewFake("ccq-fake-typing-" + t.Name())
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+
+	f.AttachClient("/dev/pts/1")
+	f.SetClientActivity(time.Now().Unix())
+
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+	return f, sw, w0, w1
+}
+
+func TestTrySwitchLocked_DefersWhileTyping(t *testing.T) {
+	t.Parallel()
+	f, sw, w0, w1 := setupTyping(t)
+
+	if sw.TrySwitchLocked(w1) {
+		t.Fatal("expected switch to be deferred while the user is typing")
+	}
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Errorf("expected to stay on %s, got %s", w0, active)
+	}
+	shell := f.Shell()
+	if len(shell) != 1 || !strings.HasSuffix(shell[0], "; ccq _reconcile") {
+		t.Errorf("expected a scheduled reconcile, got %q", shell)
+	}
+	if got := sw.PendingSwitch(); got != switcher.PendingTyping {
+		t.Errorf("expected pending switch %q, got %q", switcher.PendingTyping, got)
+	}
+
+	// Once the user has gone quiet, reconcile switches
+	f.SetClientActivity(time.Now().Add(-time.Minute).Unix())
+	if !sw.Reconcile() {
+		t.Fatal("expected reconcile to switch after the grace period")
+	}
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected active window = %s, got %s", w1, active)
+	}
+}
+
+func TestTrySwitchLocked_ActiveTriggerNotDeferred(t *testing.T) {
+	t.Parallel()
+	f, sw, w0, w1 := setupTyping(t)
+
+	// Input in the active window is what triggered the hook (prompt submit)
+	if !sw.TrySwitchLocked(w0) {
+		t.Fatal("expected switch triggered by the active window to happen")
+	}
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected active window = %s, got %s", w1, active)
+	}
+}
+
+func TestTrySwitchLocked_ZeroGraceDisables(t *testing.T) {
+	t.Parallel()
+	f, sw, _, w1 := setupTyping(t)
+
+	sw.SetTypingGrace(0)
+	if !sw.TrySwitchLocked(w1) {
+		t.Fatal("expected switch with typing suppression disabled")
+	}
+	if len(f.Shell()) != 0 {
+		t.Errorf("expected no scheduled reconcile, got %q", f.Shell())
+	}
+}
+
+func TestTrySwitch_IgnoresTyping(t *testing.T) {
+	t.Parallel()
+	_, sw, _, _ := setupTyping(t)
+
+	if !sw.TrySwitch() {
+		t.Error("expected an explicit switch request to ignore typing")
+	}
+}
+
+func TestTypingGrace(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-" + t.Name())
+	sw := switcher.New(f, queue.New(f))
+
+	if got := sw.TypingGrace(); got != switcher.DefaultTypingGrace {
+		t.Errorf("expected default %s, got %s", switcher.DefaultTypingGrace, got)
+	}
+	if err := sw.SetTypingGrace(5 * time.Second); err != nil {
+		t.Fatalf("SetTypingGrace: %v", err)
+	}
+	if got := sw.TypingGrace(); got != 5*time.Second {
+		t.Errorf("expected 5s, got %s", got)
+	}
+	if err := sw.SetTypingGrace(-time.Second); err == nil {
+		t.Error("expected error for negative grace")
+	}
+
+	// Sub-second values round up rather than disabling suppression
+	for d, want := range map[time.Duration]time.Duration{
+		500 * time.Millisecond:  time.Second,
+		1500 * time.Millisecond: 2 * time.Second,
+		0:                       0,
+	} {
+		sw.SetTypingGrace(d)
+		if got := sw.TypingGrace(); got != want {
+			t.Errorf("SetTypingGrace(%s): got %s, want %s", d, got, want)
+		}
+	}
+}
+
+func TestPendingSwitch_ActiveIdleRetriedOnReconcile(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-pending-idle")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	q := queue.New(f)
+	q.MarkIdle(w0)
+	q.MarkIdle(w1)
+
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+	if sw.TrySwitchLocked(w1) {
+		t.Fatal("expected no switch while the active window is idle")
+	}
+	if got := sw.PendingSwitch(); got != switcher.PendingActiveIdle {
+		t.Errorf("expected pending switch %q, got %q", switcher.PendingActiveIdle, got)
+	}
+
+	// w0 goes busy; the next window change fires a reconcile
+	q.MarkBusy(w0)
+	if !sw.Reconcile() {
+		t.Fatal("expected reconcile to perform the pending switch")
+	}
+	if active, _ := f.ActiveWindowID(); active != w1 {
+		t.Errorf("expected active window = %s, got %s", w1, active)
+	}
+	if got := sw.PendingSwitch(); got != "" {
+		t.Errorf("expected pending switch to be cleared, got %q", got)
+	}
+}
+
+func TestPendingSwitch_ClearedWhenNothingWaits(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-pending-clear")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/tmp")
+
+	q := queue.New(f)
+	q.MarkIdle(w0)
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+
+	// Only the active window is idle: nothing to retry
+	sw.TrySwitchLocked(w0)
+	if got := sw.PendingSwitch(); got != "" {
+		t.Errorf("expected no pending switch, got %q", got)
+	}
+
+	q.MarkIdle(w1)
+	sw.TrySwitchLocked(w1)
+	if sw.PendingSwitch() == "" {
+		t.Fatal("expected a pending switch")
+	}
+
+	// w1 is picked up by the user directly; the retry finds nothing to do
+	q.MarkBusy(w1)
+	if sw.Reconcile() {
+		t.Error("expected no switch")
+	}
+	if got := sw.PendingSwitch(); got != "" {
+		t.Errorf("expected pending switch to be cleared, got %q", got)
+	}
+}
+
+func TestReconcile_NoPendingIsNoop(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-reconcile-noop")
+	windows, _ := f.ListWindows()
+	w1, _ := f.NewWindow("/tmp")
+
+	q := queue.New(f)
+	q.MarkBusy(windows[0].ID)
+	q.MarkIdle(w1)
+	sw := switcher.New(f, q)
+	sw.SetAutoSwitch(true)
+
+	if sw.Reconcile() {
+		t.Error("expected reconcile without a pending switch to do nothing")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	f, q := setup(t)
+	sw := switcher.New(f, q)
+	sw.RetryAfter(90 * time.Second)
+	if shell := f.Shell(); len(shell) != 1 || shell[0] != "sleep 90; ccq _reconcile --retry" {
+		t.Errorf("expected a scheduled retry, got %q", shell)
+	}
+
+	// The daemon's tick retries by itself
+	sw.ReconcileOnTick()
+	sw.RetryAfter(time.Minute)
+	sw.DeferSwitch(switcher.PendingLocked, switcher.LockRetryDelay)
+	if n := len(f.Shell()); n != 1 {
+		t.Errorf("expected nothing scheduled on tick, got %q", f.Shell())
+	}
+	if got := sw.PendingSwitch(); got != switcher.PendingLocked {
+		t.Errorf("expected the deferral still recorded, got %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+package tmux
+
+// Backend is the subset of tmux operations used by the queue, switcher,
+// hook handlers and status commands. *Tmux implements it by shelling out to
+// tmux; *Fake implements it in memory for tests.
+type Backend interface {
+	// SessionName returns the name of the session the backend operates on.
+	SessionName() string
+	HasSession() bool
+
+	NewWindow(dir string) (string, error)
+	StartWindow(spec WindowSpec) (string, error)
+	// ListWindows returns all windows, reading the given user options in the
+	// same call (see Tmux.ListWindows).
+	ListWindows(options ...string) ([]WindowInfo, error)
+	SelectWindow(windowID string) error
+	KillWindow(windowID string) error
+	ActiveWindowID() (string, error)
+	WindowIDFromPane(paneID string) (string, error)
+	SendKeys(target, text string, enter bool) error
+
+	SetWindowOption(windowID, key, value string) error
+	GetWindowOption(windowID, key string) (string, error)
+	UnsetWindowOption(windowID, key string) error
+	SetSessionOption(key, value string) error
+	GetSessionOption(key string) (string, error)
+	UnsetSessionOption(key string) error
+
+	ListClients() []string
+	// CurrentClient returns the TTY of the client most recently used with the
+	// session, or "" if none is attached.
+	CurrentClient() string
+	// ClientActivity returns the Unix time of the latest input from any
+	// attached client, or 0 if none is attached.
+	ClientActivity() int64
+	// DetachClient detaches the client on the given TTY, or every client
+	// attached to the session if tty is "".
+	DetachClient(tty string) error
+
+	// RunShell runs a shell command in the background from the tmux server.
+	RunShell(command string) error
+}
+
+var (
+	_ Backend = (*Tmux)(nil)
+	_ Backend = (*Fake)(nil)
+)
@@ -0,0 +1,378 @@
+package tmux
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fake is an in-memory Backend for tests. It models a single session with
+// windows, window and session options, attached clients and the active
+// window, without a tmux server. It is safe for concurrent use.
+type Fake struct {
+	mu       sync.Mutex
+	session  string
+	exists   bool
+	windows  []*fakeWindow // ordered by index
+	active   string
+	nextID   int
+	options  map[string]string
+	clients  []string
+	detached []string
+	activity int64
+	shell    []string
+
+	calls map[string]int
+}
+
+type fakeWindow struct {
+	id      string
+	index   int
+	dir     string
+	options map[string]string
+	keys    [][]string
+	spec    WindowSpec
+}
+
+// NewFake creates a fake session with one window, like a fresh
+// `tmux new-session`.
+func NewFake(session string) *Fake {
+	f := &Fake{session: session, exists: true, options: make(map[string]string), calls: make(map[string]int)}
+	f.active = f.addWindow("/")
+	return f
+}
+
+func (f *Fake) addWindow(dir string) string {
+	index := 0
+	if n := len(f.windows); n > 0 {
+		index = f.windows[n-1].index + 1
+	}
+	w := &fakeWindow{
+		id:      "@" + strconv.Itoa(f.nextID),
+		index:   index,
+		dir:     dir,
+		options: make(map[string]string),
+	}
+	f.nextID++
+	f.windows = append(f.windows, w)
+	return w.id
+}
+
+func (f *Fake) window(windowID string) *fakeWindow {
+	for _, w := range f.windows {
+		if w.id == windowID {
+			return w
+		}
+	}
+	return nil
+}
+
+func (f *Fake) SessionName() string { return f.session }
+
+func (f *Fake) HasSession() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.exists
+}
+
+// NewWindow adds a window without selecting it (like `new-window -d`).
+func (f *Fake) NewWindow(dir string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.exists {
+		return "", fmt.Errorf("can't find session: %s", f.session)
+	}
+	return f.addWindow(dir), nil
+}
+
+// StartWindow adds a window without selecting it, recording spec (see Spec).
+func (f *Fake) StartWindow(spec WindowSpec) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.exists {
+		return "", fmt.Errorf("can't find session: %s", f.session)
+	}
+	id := f.addWindow(spec.Dir)
+	f.window(id).spec = spec
+	return id, nil
+}
+
+func (f *Fake) ListWindows(options ...string) ([]WindowInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.exists {
+		return nil, fmt.Errorf("can't find session: %s", f.session)
+	}
+	f.calls["ListWindows"]++
+	windows := make([]WindowInfo, 0, len(f.windows))
+	for _, w := range f.windows {
+		info := WindowInfo{
+			ID:     w.id,
+			Index:  strconv.Itoa(w.index),
+			Name:   "zsh",
+			Named:  w.spec.Name != "",
+			Active: w.id == f.active,
+			Dir:    w.dir,
+			Option: make(map[string]string, len(options)),
+		}
+		for _, key := range options {
+			info.Option[key] = w.options[key]
+		}
+		if info.Named {
+			info.Name = w.spec.Name
+		}
+		windows = append(windows, info)
+	}
+	return windows, nil
+}
+
+func (f *Fake) SelectWindow(windowID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.window(windowID) == nil {
+		return fmt.Errorf("can't find window: %s", windowID)
+	}
+	f.active = windowID
+	return nil
+}
+
+func (f *Fake) ActiveWindowID() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["ActiveWindowID"]++
+	if !f.exists || f.active == "" {
+		return "", fmt.Errorf("can't find session: %s", f.session)
+	}
+	return f.active, nil
+}
+
+// WindowIDFromPane resolves pane IDs as returned by PaneID.
+func (f *Fake) WindowIDFromPane(paneID string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := "@" + strings.TrimPrefix(paneID, "%")
+	if f.window(id) == nil {
+		return "", fmt.Errorf("can't find pane: %s", paneID)
+	}
+	return id, nil
+}
+
+func (f *Fake) SendKeys(target, text string, enter bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w := f.window(target)
+	if w == nil {
+		return fmt.Errorf("can't find window: %s", target)
+	}
+	w.keys = append(w.keys, sendKeysArgs(target, text, enter)...)
+	return nil
+}
+
+func (f *Fake) SetWindowOption(windowID, key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["SetWindowOption"]++
+	w := f.window(windowID)
+	if w == nil {
+		return fmt.Errorf("can't find window: %s", windowID)
+	}
+	w.options[key] = value
+	return nil
+}
+
+// GetWindowOption returns "" for unset options or unknown windows, like Tmux.
+func (f *Fake) GetWindowOption(windowID, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["GetWindowOption"]++
+	if w := f.window(windowID); w != nil {
+		return w.options[key], nil
+	}
+	return "", nil
+}
+
+func (f *Fake) UnsetWindowOption(windowID, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["UnsetWindowOption"]++
+	w := f.window(windowID)
+	if w == nil {
+		return fmt.Errorf("can't find window: %s", windowID)
+	}
+	delete(w.options, key)
+	return nil
+}
+
+func (f *Fake) SetSessionOption(key, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.options[key] = value
+	return nil
+}
+
+func (f *Fake) GetSessionOption(key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls["GetSessionOption"]++
+	return f.options[key], nil
+}
+
+func (f *Fake) UnsetSessionOption(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.options, key)
+	return nil
+}
+
+func (f *Fake) ListClients() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.clients...)
+}
+
+// CurrentClient returns the most recently attached client.
+func (f *Fake) CurrentClient() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.clients) == 0 {
+		return ""
+	}
+	return f.clients[len(f.clients)-1]
+}
+
+func (f *Fake) DetachClient(tty string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var kept []string
+	for _, c := range f.clients {
+		if tty == "" || c == tty {
+			f.detached = append(f.detached, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	f.clients = kept
+	return nil
+}
+
+func (f *Fake) ClientActivity() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.clients) == 0 {
+		return 0
+	}
+	return f.activity
+}
+
+// RunShell records the command instead of running it; see Shell.
+func (f *Fake) RunShell(command string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.shell = append(f.shell, command)
+	return nil
+}
+
+// Test helpers
+
+// SetClientActivity sets the time of the latest client input.
+func (f *Fake) SetClientActivity(ts int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.activity = ts
+}
+
+// Shell returns the commands passed to RunShell.
+func (f *Fake) Shell() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.shell...)
+}
+
+// PaneID returns the ID of the window's pane, for use with WindowIDFromPane.
+func (f *Fake) PaneID(windowID string) string {
+	return "%" + strings.TrimPrefix(windowID, "@")
+}
+
+// AttachClient simulates a client attaching on the given TTY.
+func (f *Fake) AttachClient(tty string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.clients = append(f.clients, tty)
+}
+
+// Detached returns the TTYs of clients detached via DetachClient.
+func (f *Fake) Detached() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.detached...)
+}
+
+// KillWindow removes a window, selecting the next one if it was active.
+func (f *Fake) KillWindow(windowID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, w := range f.windows {
+		if w.id != windowID {
+			continue
+		}
+		f.windows = append(f.windows[:i], f.windows[i+1:]...)
+		if f.active == windowID {
+			f.active = ""
+			if len(f.windows) > 0 {
+				f.active = f.windows[min(i, len(f.windows)-1)].id
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("can't find window: %s", windowID)
+}
+
+// KillSession removes the session and all its windows.
+func (f *Fake) KillSession() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.exists = false
+	f.windows = nil
+	f.active = ""
+}
+
+// SetPanePath changes the current directory of the window's pane.
+func (f *Fake) SetPanePath(windowID, dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.window(windowID); w != nil {
+		w.dir = dir
+	}
+}
+
+// Calls returns how many times a read method (ListWindows, ActiveWindowID,
+// GetWindowOption, GetSessionOption) or a window option write
+// (SetWindowOption, UnsetWindowOption) has been called, standing in for the
+// number of tmux processes the real backend would spawn.
+func (f *Fake) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+// Spec returns the spec a window was started with (zero for NewWindow).
+func (f *Fake) Spec(windowID string) WindowSpec {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.window(windowID); w != nil {
+		return w.spec
+	}
+	return WindowSpec{}
+}
+
+// SentKeys returns the send-keys commands the real backend would have run
+// for a window, e.g. {"send-keys", "-t", "@1", "-l", "hi"}.
+func (f *Fake) SentKeys(windowID string) [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if w := f.window(windowID); w != nil {
+		return append([][]string(nil), w.keys...)
+	}
+	return nil
+}
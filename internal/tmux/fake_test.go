@@ -0,0 +1,75 @@
+package tmux_test
+
+import (
+	"testing"
+
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+func TestFake_WindowsAndOptions(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake")
+
+	windows, err := f.ListWindows()
+	if err != nil {
+		t.Fatalf("ListWindows: %v", err)
+	}
+	if len(windows) != 1 || !windows[0].Active || windows[0].Index != "0" {
+		t.Fatalf("expected one active window at index 0, got %+v", windows)
+	}
+	w0 := windows[0].ID
+
+	w1, err := f.NewWindow("/tmp")
+	if err != nil {
+		t.Fatalf("NewWindow: %v", err)
+	}
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Errorf("NewWindow should not change the active window, got %s", active)
+	}
+	if windows, _ := f.ListWindows(); windows[1].Dir != "/tmp" {
+		t.Errorf("expected pane path /tmp, got %q", windows[1].Dir)
+	}
+
+	f.SetWindowOption(w1, "@ccq_state", "idle")
+	if val, _ := f.GetWindowOption(w1, "@ccq_state"); val != "idle" {
+		t.Errorf("expected idle, got %q", val)
+	}
+	f.UnsetWindowOption(w1, "@ccq_state")
+	if val, _ := f.GetWindowOption(w1, "@ccq_state"); val != "" {
+		t.Errorf("expected unset option, got %q", val)
+	}
+
+	if id, _ := f.WindowIDFromPane(f.PaneID(w1)); id != w1 {
+		t.Errorf("WindowIDFromPane = %q, want %s", id, w1)
+	}
+
+	if err := f.SelectWindow("@99"); err == nil {
+		t.Error("expected error selecting unknown window")
+	}
+
+	f.SelectWindow(w1)
+	f.KillWindow(w1)
+	if active, _ := f.ActiveWindowID(); active != w0 {
+		t.Errorf("expected active to move to %s after kill, got %s", w0, active)
+	}
+}
+
+func TestFake_Clients(t *testing.T) {
+	t.Parallel()
+	f := tmux.NewFake("ccq-fake-clients")
+	f.AttachClient("/dev/ttys001")
+	f.AttachClient("/dev/ttys002")
+
+	f.DetachClient("/dev/ttys001")
+	if clients := f.ListClients(); len(clients) != 1 || clients[0] != "/dev/ttys002" {
+		t.Errorf("expected only ttys002 attached, got %v", clients)
+	}
+
+	f.DetachClient("")
+	if clients := f.ListClients(); len(clients) != 0 {
+		t.Errorf("expected no clients, got %v", clients)
+	}
+	if detached := f.Detached(); len(detached) != 2 {
+		t.Errorf("expected 2 detached clients, got %v", detached)
+	}
+}
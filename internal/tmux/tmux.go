@@ -3,6 +3,8 @@ package tmux
 
 import (
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -22,6 +24,11 @@ func New(session string) *Tmux {
 	return &Tmux{Session: session}
 }
 
+// SessionName returns the name of the session.
+func (t *Tmux) SessionName() string {
+	return t.Session
+}
+
 // Run executes an arbitrary tmux command.
 func (t *Tmux) Run(args ...string) (string, error) {
 	cmd := exec.Command("tmux", args...)
@@ -41,6 +48,14 @@ func (t *Tmux) NewSession() error {
 	return err
 }
 
+// NewSessionWith creates a new detached session whose first window is
+// described by spec.
+func (t *Tmux) NewSessionWith(spec WindowSpec) error {
+	args := []string{"new-session", "-d", "-s", t.Session}
+	_, err := t.Run(append(args, spec.args()...)...)
+	return err
+}
+
 // KillSession destroys the session.
 func (t *Tmux) KillSession() error {
 	_, err := t.Run("kill-session", "-t", t.Session)
@@ -53,35 +68,104 @@ func (t *Tmux) NewWindow(dir string) (string, error) {
 	return t.Run("new-window", "-d", "-t", t.Session, "-c", dir, "-P", "-F", "#{window_id}")
 }
 
+// WindowSpec describes a window to create.
+type WindowSpec struct {
+	Dir     string
+	Name    string            // window name; "" lets tmux name it after the running command
+	Command string            // shell command run instead of the default shell; the window closes when it exits
+	Env     map[string]string // environment variables set for the command
+}
+
+// args returns the new-window/new-session arguments for the spec.
+func (s WindowSpec) args() []string {
+	var args []string
+	if s.Dir != "" {
+		args = append(args, "-c", s.Dir)
+	}
+	if s.Name != "" {
+		args = append(args, "-n", s.Name)
+	}
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "-e", k+"="+s.Env[k])
+	}
+	if s.Command != "" {
+		args = append(args, s.Command)
+	}
+	return args
+}
+
+// StartWindow creates a window as described by spec without selecting it.
+// Returns the window ID.
+func (t *Tmux) StartWindow(spec WindowSpec) (string, error) {
+	args := []string{"new-window", "-d", "-t", t.Session, "-P", "-F", "#{window_id}"}
+	return t.Run(append(args, spec.args()...)...)
+}
+
+// KillWindow closes a window and the processes running in it.
+func (t *Tmux) KillWindow(windowID string) error {
+	_, err := t.Run("kill-window", "-t", windowID)
+	return err
+}
+
 // WindowInfo holds metadata about a tmux window.
 type WindowInfo struct {
 	ID     string
 	Index  string
 	Name   string
+	Named  bool // named explicitly (automatic-rename is off)
 	Active bool
+	Dir    string            // current path of the window's active pane
+	Option map[string]string // user options requested from ListWindows ("" if unset)
 }
 
-// ListWindows returns all windows in the session.
-func (t *Tmux) ListWindows() ([]WindowInfo, error) {
-	out, err := t.Run("list-windows", "-t", t.Session, "-F", "#{window_id}\t#{window_index}\t#{window_name}\t#{window_active}")
+// windowFormat lists the fields ListWindows always reads, tab-separated.
+const windowFormat = "#{window_id}\t#{window_index}\t#{window_name}\t#{window_active}\t#{pane_current_path}\t#{automatic-rename}"
+
+// ListWindows returns all windows in the session. The given user options
+// (e.g. "@ccq_state") are read in the same list-windows call, so a full
+// snapshot of the session costs a single tmux process.
+// Option values must not contain tabs or newlines.
+func (t *Tmux) ListWindows(options ...string) ([]WindowInfo, error) {
+	format := windowFormat
+	for _, key := range options {
+		format += "\t#{" + key + "}"
+	}
+	out, err := t.Run("list-windows", "-t", t.Session, "-F", format)
 	if err != nil {
 		return nil, err
 	}
+	fields := 6 + len(options)
 	var windows []WindowInfo
 	for _, line := range strings.Split(out, "\n") {
 		if line == "" {
 			continue
 		}
-		parts := strings.SplitN(line, "\t", 4)
+		parts := strings.SplitN(line, "\t", fields)
 		if len(parts) < 4 {
 			continue
 		}
-		windows = append(windows, WindowInfo{
+		// Run trims trailing whitespace, dropping empty trailing fields of the last line.
+		for len(parts) < fields {
+			parts = append(parts, "")
+		}
+		w := WindowInfo{
 			ID:     parts[0],
 			Index:  parts[1],
 			Name:   parts[2],
+			Named:  parts[5] == "0",
 			Active: parts[3] == "1",
-		})
+			Dir:    parts[4],
+			Option: make(map[string]string, len(options)),
+		}
+		for i, key := range options {
+			w.Option[key] = parts[6+i]
+		}
+		windows = append(windows, w)
 	}
 	return windows, nil
 }
@@ -133,14 +217,35 @@ func (t *Tmux) GetSessionOption(key string) (string, error) {
 	return out, nil
 }
 
-// SendKeys sends keystrokes to a window. If enter is true, appends Enter.
-func (t *Tmux) SendKeys(target, keys string, enter bool) error {
-	args := []string{"send-keys", "-t", target, keys}
+// UnsetSessionOption removes a user option from the session.
+func (t *Tmux) UnsetSessionOption(key string) error {
+	_, err := t.Run("set-option", "-u", "-t", t.Session, key)
+	return err
+}
+
+// SendKeys types text into a window. It is sent literally, so words such as
+// Enter or C-c are not read as key names. If enter is true, Enter is pressed
+// afterwards.
+func (t *Tmux) SendKeys(target, text string, enter bool) error {
+	for _, args := range sendKeysArgs(target, text, enter) {
+		if _, err := t.Run(args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendKeysArgs returns the send-keys commands for SendKeys: the text with -l,
+// then Enter as a key of its own.
+func sendKeysArgs(target, text string, enter bool) [][]string {
+	var cmds [][]string
+	if text != "" {
+		cmds = append(cmds, []string{"send-keys", "-t", target, "-l", text})
+	}
 	if enter {
-		args = append(args, "Enter")
+		cmds = append(cmds, []string{"send-keys", "-t", target, "Enter"})
 	}
-	_, err := t.Run(args...)
-	return err
+	return cmds
 }
 
 // WindowIDFromPane returns the window ID containing the given pane.
@@ -148,11 +253,6 @@ func (t *Tmux) WindowIDFromPane(paneID string) (string, error) {
 	return t.Run("display-message", "-t", paneID, "-p", "#{window_id}")
 }
 
-// GetWindowPanePath returns the current working directory of the first pane in the window.
-func (t *Tmux) GetWindowPanePath(windowID string) (string, error) {
-	return t.Run("display-message", "-t", windowID, "-p", "#{pane_current_path}")
-}
-
 // ListClients returns the TTYs of clients attached to the session.
 func (t *Tmux) ListClients() []string {
 	out, err := t.Run("list-clients", "-t", t.Session, "-F", "#{client_tty}")
@@ -167,3 +267,61 @@ func (t *Tmux) ListClients() []string {
 	}
 	return clients
 }
+
+// CurrentClient returns the TTY of the client tmux considers current for the
+// session (the most recently active one), or "" if none is attached.
+func (t *Tmux) CurrentClient() string {
+	if len(t.ListClients()) == 0 {
+		return ""
+	}
+	out, err := t.Run("display-message", "-p", "-t", t.Session, "#{client_tty}")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// DisplayMessage shows a message in a client's status line, or in the
+// current client's if tty is "".
+func (t *Tmux) DisplayMessage(tty, msg string) error {
+	args := []string{"display-message"}
+	if tty != "" {
+		args = append(args, "-c", tty)
+	}
+	_, err := t.Run(append(args, msg)...)
+	return err
+}
+
+// DetachClient detaches the client on the given TTY, or all clients attached
+// to the session if tty is "".
+func (t *Tmux) DetachClient(tty string) error {
+	if tty == "" {
+		_, err := t.Run("detach-client", "-s", t.Session)
+		return err
+	}
+	_, err := t.Run("detach-client", "-t", tty)
+	return err
+}
+
+// ClientActivity returns the Unix time of the most recent input from any
+// client attached to the session, or 0 if no client is attached.
+func (t *Tmux) ClientActivity() int64 {
+	out, err := t.Run("list-clients", "-t", t.Session, "-F", "#{client_activity}")
+	if err != nil {
+		return 0
+	}
+	var latest int64
+	for _, line := range strings.Split(out, "\n") {
+		if ts, err := strconv.ParseInt(line, 10, 64); err == nil && ts > latest {
+			latest = ts
+		}
+	}
+	return latest
+}
+
+// RunShell runs a shell command in the background from the tmux server
+// (run-shell -b), so it outlives the calling process.
+func (t *Tmux) RunShell(command string) error {
+	_, err := t.Run("run-shell", "-b", command)
+	return err
+}
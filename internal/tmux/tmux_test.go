@@ -2,7 +2,9 @@
 package tmux_test
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/jingikim/ccq/internal/tmux"
 )
@@ -87,6 +89,84 @@ func TestWindowAndOptions(t *testing.T) {
 	}
 }
 
+func TestStartWindow_RunsCommand(t *testing.T) {
+	if !tmux.IsInstalled() {
+		t.Skip("tmux not installed")
+	}
+
+	tm := tmux.New("ccq-test-start-window")
+	if err := tm.NewSession(); err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer tm.KillSession()
+
+	windowID, err := tm.StartWindow(tmux.WindowSpec{
+		Dir:     "/tmp",
+		Name:    "api",
+		Command: `echo "$CCQ_TEST_VAR $(pwd)"; sleep 5`,
+		Env:     map[string]string{"CCQ_TEST_VAR": "hello"},
+	})
+	if err != nil {
+		t.Fatalf("StartWindow: %v", err)
+	}
+
+	var out string
+	for i := 0; i < 50 && !strings.Contains(out, "hello"); i++ {
+		time.Sleep(20 * time.Millisecond)
+		out, _ = tm.Run("capture-pane", "-p", "-t", windowID)
+	}
+	if !strings.Contains(out, "hello /tmp") {
+		t.Errorf("expected command output with env and dir, got %q", out)
+	}
+
+	windows, _ := tm.ListWindows()
+	for _, w := range windows {
+		if w.ID == windowID && (w.Name != "api" || !w.Named) {
+			t.Errorf("expected window named api, got %q (named %v)", w.Name, w.Named)
+		}
+		if w.ID != windowID && w.Named {
+			t.Errorf("expected %s to be named automatically", w.ID)
+		}
+	}
+}
+
+func TestListWindowsWithOptions(t *testing.T) {
+	if !tmux.IsInstalled() {
+		t.Skip("tmux not installed")
+	}
+
+	tm := tmux.New("ccq-test-list-options")
+	if err := tm.NewSession(); err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer tm.KillSession()
+
+	w1, _ := tm.NewWindow("/tmp")
+	tm.SetWindowOption(w1, "@ccq_state", "idle")
+
+	windows, err := tm.ListWindows("@ccq_state", "@ccq_idle_since")
+	if err != nil {
+		t.Fatalf("ListWindows: %v", err)
+	}
+	if len(windows) != 2 {
+		t.Fatalf("expected 2 windows, got %d", len(windows))
+	}
+	// The last window has empty trailing options; it must still be listed
+	last := windows[1]
+	if last.ID != w1 {
+		t.Fatalf("expected last window %s, got %s", w1, last.ID)
+	}
+	if last.Option["@ccq_state"] != "idle" || last.Option["@ccq_idle_since"] != "" {
+		t.Errorf("unexpected options: %v", last.Option)
+	}
+	if last.Dir != "/tmp" && last.Dir != "/private/tmp" {
+		t.Errorf("expected pane path /tmp, got %q", last.Dir)
+	}
+	if windows[0].Option["@ccq_state"] != "" {
+		t.Errorf("expected unset option on first window, got %q", windows[0].Option["@ccq_state"])
+	}
+}
+
 func TestListClients(t *testing.T) {
 	if !tmux.IsInstalled() {
 		t.Skip("tmux not installed")
@@ -105,3 +185,32 @@ func TestListClients(t *testing.T) {
 		t.Errorf("expected 0 clients for detached session, got %d", len(clients))
 	}
 }
+
+func TestSendKeys_Literal(t *testing.T) {
+	if !tmux.IsInstalled() {
+		t.Skip("tmux not installed")
+	}
+
+	tm := tmux.New("ccq-test-send-keys")
+	if err := tm.NewSession(); err != nil {
+		t.Fatalf("NewSession: %v", err)
+	}
+	defer tm.KillSession()
+
+	windows, _ := tm.ListWindows()
+	w0 := windows[0].ID
+
+	// A prompt that is exactly a key name is typed, not pressed
+	if err := tm.SendKeys(w0, "C-c", false); err != nil {
+		t.Fatalf("SendKeys: %v", err)
+	}
+	var pane string
+	for i := 0; i < 20; i++ {
+		pane, _ = tm.Run("capture-pane", "-p", "-t", w0)
+		if strings.Contains(pane, "C-c") {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("expected C-c typed into the pane, got:\n%s", pane)
+}
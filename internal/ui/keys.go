@@ -0,0 +1,63 @@
+package ui
+
+import "unicode/utf8"
+
+// Key is a key press: a printable character as itself ("j"), or one of the
+// named keys below.
+type Key string
+
+// Named keys.
+const (
+	KeyUp        Key = "<up>"
+	KeyDown      Key = "<down>"
+	KeyEnter     Key = "<enter>"
+	KeyEsc       Key = "<esc>"
+	KeyBackspace Key = "<backspace>"
+	KeyCtrlC     Key = "<ctrl-c>"
+)
+
+// parseKeys splits raw terminal input into key presses. Unknown escape
+// sequences and control characters are dropped.
+func parseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b:
+			if len(b) >= 3 && (b[1] == '[' || b[1] == 'O') {
+				switch b[2] {
+				case 'A':
+					keys = append(keys, KeyUp)
+				case 'B':
+					keys = append(keys, KeyDown)
+				}
+				// Skip the rest of the sequence (parameters up to the final byte)
+				n := 2
+				for n < len(b) && (b[n] < 0x40 || b[n] > 0x7e) {
+					n++
+				}
+				b = b[min(n+1, len(b)):]
+				continue
+			}
+			keys = append(keys, KeyEsc)
+			b = b[1:]
+		case b[0] == '\r' || b[0] == '\n':
+			keys = append(keys, KeyEnter)
+			b = b[1:]
+		case b[0] == 0x7f || b[0] == 0x08:
+			keys = append(keys, KeyBackspace)
+			b = b[1:]
+		case b[0] == 0x03:
+			keys = append(keys, KeyCtrlC)
+			b = b[1:]
+		case b[0] < 0x20:
+			b = b[1:]
+		default:
+			r, n := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, Key(string(r)))
+			}
+			b = b[n:]
+		}
+	}
+	return keys
+}
@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// refreshInterval is how often the dashboard reloads the session while idle,
+// matching the status bar refresh.
+const refreshInterval = time.Second
+
+// Run shows the dashboard on the controlling terminal until the user quits or
+// jumps to a window.
+func Run(tm tmux.Backend) error {
+	saved, err := stty("-g")
+	if err != nil {
+		return fmt.Errorf("ccq ui needs a terminal: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return err
+	}
+	// Alternate screen, cursor hidden; both restored on exit
+	fmt.Print("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[?25h\x1b[?1049l")
+		stty(saved)
+	}()
+
+	keys := make(chan []Key)
+	go readKeys(keys)
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	app := New(tm)
+	for {
+		width, height := termSize()
+		fmt.Print("\x1b[H" + app.View(width, height, time.Now()))
+
+		select {
+		case ks, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, k := range ks {
+				if app.HandleKey(k) {
+					return nil
+				}
+			}
+		case <-ticker.C:
+			app.Reload()
+		case <-resize:
+		}
+	}
+}
+
+// readKeys sends batches of key presses read from stdin, closing keys when
+// stdin ends.
+func readKeys(keys chan<- []Key) {
+	buf := make([]byte, 256)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			keys <- parseKeys(buf[:n])
+		}
+		if err != nil {
+			close(keys)
+			return
+		}
+	}
+}
+
+// stty runs stty on the controlling terminal and returns its output.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// termSize returns the terminal width and height, falling back to 80x24.
+func termSize() (width, height int) {
+	out, err := stty("size")
+	if err == nil {
+		if rows, cols, ok := strings.Cut(out, " "); ok {
+			h, herr := strconv.Atoi(rows)
+			w, werr := strconv.Atoi(cols)
+			if herr == nil && werr == nil && w > 0 && h > 0 {
+				return w, h
+			}
+		}
+	}
+	return 80, 24
+}
@@ -0,0 +1,344 @@
+// Package ui implements `ccq ui`, a full-screen dashboard of the ccq session
+// meant to run in a tmux popup. It lists every window with its state and acts
+// on the selected one through the queue and tmux packages. Terminal handling
+// uses stty and ANSI escapes only (see term.go).
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/switcher"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// DefaultSnooze is the duration prefilled in the snooze prompt, matching the
+// prefix + S binding.
+const DefaultSnooze = "20m"
+
+type mode int
+
+const (
+	modeList    mode = iota
+	modeInput        // editing a line at the bottom (snooze duration, prompt)
+	modeConfirm      // waiting for y/n
+)
+
+// App is the dashboard state. HandleKey updates it, View renders it and Run
+// connects both to the terminal.
+type App struct {
+	tm tmux.Backend
+	q  *queue.Queue
+	sw *switcher.Switcher
+
+	sess     status.Session
+	err      error
+	selected string // ID of the selected window, kept across reloads
+	next     string // ID of the window auto-switch would select next
+
+	mode    mode
+	prompt  string
+	input   []rune
+	submit  func(input string) error // runs when the input or confirm prompt is accepted
+	message string                   // feedback shown until the next key press
+}
+
+// New creates an App for the given tmux backend and loads the session.
+func New(tm tmux.Backend) *App {
+	q := queue.New(tm)
+	a := &App{tm: tm, q: q, sw: switcher.New(tm, q)}
+	a.Reload()
+	return a
+}
+
+// Reload re-reads the session. The selection stays on the same window; if it
+// is gone (or nothing is selected yet) the active window is selected.
+func (a *App) Reload() {
+	a.sess, a.err = status.Load(a.tm)
+	if a.err != nil {
+		return
+	}
+
+	a.next = ""
+	for _, id := range a.sess.Queue {
+		if w, ok := a.window(id); ok && !w.Active {
+			a.next = id
+			break
+		}
+	}
+
+	if _, ok := a.window(a.selected); ok || len(a.sess.Windows) == 0 {
+		return
+	}
+	a.selected = a.sess.Windows[0].ID
+	for _, w := range a.sess.Windows {
+		if w.Active {
+			a.selected = w.ID
+		}
+	}
+}
+
+// Selected returns the ID of the selected window.
+func (a *App) Selected() string {
+	return a.selected
+}
+
+func (a *App) window(id string) (status.Window, bool) {
+	for _, w := range a.sess.Windows {
+		if w.ID == id {
+			return w, true
+		}
+	}
+	return status.Window{}, false
+}
+
+// HandleKey applies a key press and reports whether the UI should exit.
+func (a *App) HandleKey(k Key) (quit bool) {
+	a.message = ""
+	switch a.mode {
+	case modeInput:
+		a.handleInput(k)
+		return false
+	case modeConfirm:
+		a.mode = modeList
+		if k == "y" || k == "Y" {
+			a.run(a.submit, "")
+		}
+		return false
+	}
+
+	w, ok := a.window(a.selected)
+	switch k {
+	case "q", KeyEsc, KeyCtrlC:
+		return true
+	case "j", KeyDown:
+		a.move(1)
+	case "k", KeyUp:
+		a.move(-1)
+	}
+	if !ok {
+		return false
+	}
+
+	switch k {
+	case KeyEnter:
+		if err := a.sw.Focus(w.ID, switcher.ReasonUI); err != nil {
+			a.message = err.Error()
+			return false
+		}
+		return true
+	case "s":
+		a.ask(fmt.Sprintf("snooze #%s for (or off): ", w.Index), DefaultSnooze, func(in string) error {
+			return a.snooze(w.ID, in)
+		})
+	case "p":
+		a.run(func(string) error { return a.q.SetPriority(w.ID, nextPriority(w.Priority)) }, "")
+	case "x":
+		a.run(func(string) error { return a.q.SetExcluded(w.ID, !w.Excluded) }, "")
+	case "i":
+		a.ask(fmt.Sprintf("send to #%s: ", w.Index), "", func(in string) error {
+			return a.send(w.ID, in)
+		})
+	case "D":
+		a.mode = modeConfirm
+		a.prompt = fmt.Sprintf("kill window #%s %s? (y/n)", w.Index, w.Name)
+		a.submit = func(string) error { return a.tm.KillWindow(w.ID) }
+	}
+	return false
+}
+
+// handleInput edits the input line; Enter submits it, Esc cancels.
+func (a *App) handleInput(k Key) {
+	switch k {
+	case KeyEnter:
+		a.mode = modeList
+		a.run(a.submit, strings.TrimSpace(string(a.input)))
+	case KeyEsc, KeyCtrlC:
+		a.mode = modeList
+	case KeyBackspace:
+		if len(a.input) > 0 {
+			a.input = a.input[:len(a.input)-1]
+		}
+	default:
+		if r := []rune(string(k)); len(r) == 1 {
+			a.input = append(a.input, r[0])
+		}
+	}
+}
+
+// ask switches to input mode with the given prompt and initial text.
+func (a *App) ask(prompt, initial string, submit func(string) error) {
+	a.mode = modeInput
+	a.prompt = prompt
+	a.input = []rune(initial)
+	a.submit = submit
+}
+
+// run performs an action, showing its error, and reloads the session.
+func (a *App) run(action func(string) error, input string) {
+	if err := action(input); err != nil {
+		a.message = err.Error()
+	}
+	a.Reload()
+}
+
+func (a *App) move(delta int) {
+	for i, w := range a.sess.Windows {
+		if w.ID == a.selected {
+			i = min(max(i+delta, 0), len(a.sess.Windows)-1)
+			a.selected = a.sess.Windows[i].ID
+			return
+		}
+	}
+}
+
+func (a *App) snooze(windowID, in string) error {
+	if in == "off" {
+		return a.q.Unsnooze(windowID)
+	}
+	d, err := time.ParseDuration(in)
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid duration %q (e.g. 20m, 1h30m, off)", in)
+	}
+	if err := a.q.Snooze(windowID, d); err != nil {
+		return err
+	}
+	a.sw.RetryAfter(d)
+	return nil
+}
+
+// send types a prompt into the window's pane and submits it.
+func (a *App) send(windowID, text string) error {
+	if text == "" {
+		return nil
+	}
+	return a.tm.SendKeys(windowID, text, true)
+}
+
+// nextPriority cycles normal → high → low → normal.
+func nextPriority(p string) string {
+	switch p {
+	case queue.PriorityHigh:
+		return queue.PriorityLow
+	case queue.PriorityLow:
+		return queue.PriorityNormal
+	}
+	return queue.PriorityHigh
+}
+
+const (
+	reverseOn  = "\x1b[7m"
+	reverseOff = "\x1b[27m"
+	boldOn     = "\x1b[1m"
+	boldOff    = "\x1b[22m"
+)
+
+const helpLine = "enter jump  s snooze  p priority  x exclude  i send prompt  D kill  q quit"
+
+// View renders the screen for a terminal of the given size. Lines are
+// separated by "\r\n" since the terminal is in raw mode.
+func (a *App) View(width, height int, now time.Time) string {
+	var lines []string
+	if a.err != nil {
+		lines = append(lines, fit(a.err.Error(), width))
+	} else {
+		auto := "off"
+		if a.sess.AutoSwitch {
+			auto = "on"
+		}
+		lines = append(lines,
+			boldOn+fit(fmt.Sprintf("ccq: %d windows, auto-switch %s, policy %s",
+				len(a.sess.Windows), auto, a.sess.Policy), width)+boldOff,
+			"",
+			fit(fmt.Sprintf("   %-4s %-15s %-5s %-18s %5s  %-16s %-16s %s",
+				"#", "NAME", "STATE", "SUBSTATE", "IDLE", "GIT", "DIR", "NOTE"), width),
+		)
+		for _, w := range a.sess.Windows {
+			row := fit(a.row(w, now), width)
+			if w.ID == a.selected {
+				row = reverseOn + row + strings.Repeat(" ", max(width-len([]rune(row)), 0)) + reverseOff
+			}
+			lines = append(lines, row)
+		}
+	}
+
+	// Keep the prompt and help at the bottom, dropping rows that do not fit
+	footer := []string{fit(a.footer(), width), fit(helpLine, width)}
+	if room := max(height-len(footer), 0); len(lines) > room {
+		lines = lines[:room]
+	}
+	for len(lines)+len(footer) < height {
+		lines = append(lines, "")
+	}
+	lines = append(lines, footer...)
+	return strings.Join(lines, "\x1b[K\r\n") + "\x1b[K"
+}
+
+// row formats one window: the → marker on the next auto-switch target, then
+// icon, index, name with priority/exclude marks, state, idle time, git status,
+// directory and the latest notification (or tool in use).
+func (a *App) row(w status.Window, now time.Time) string {
+	marker := " "
+	if w.ID == a.next {
+		marker = "→"
+	}
+	name := w.Name
+	if w.Profile != "" {
+		name += "[" + w.Profile + "]"
+	}
+	name += w.PriorityMark() + w.ExcludedMark()
+
+	var idle string
+	if w.IsIdle() && w.IdleSince > 0 {
+		idle = status.FormatDuration(now.Sub(time.Unix(w.IdleSince, 0)))
+	}
+
+	var notes []string
+	if w.SnoozedUntil > 0 {
+		notes = append(notes, "snoozed "+status.FormatDuration(time.Unix(w.SnoozedUntil, 0).Sub(now)))
+	}
+	switch {
+	case w.IsIdle() && w.Notification != "":
+		notes = append(notes, w.Notification)
+	case w.State == "busy" && w.LastTool != "":
+		notes = append(notes, w.LastTool)
+	}
+
+	var gitStatus string
+	if w.Git != nil {
+		gitStatus = w.Git.String()
+	}
+	dir := filepath.Base(w.Dir)
+	if w.Dir == "" {
+		dir = ""
+	}
+	return fmt.Sprintf("%s%s #%-3s %-15s %-5s %-18s %5s  %-16s %-16s %s",
+		marker, w.Icon(), w.Index, fit(name, 15), w.State, w.Substate, idle,
+		fit(gitStatus, 16), fit(dir, 16), strings.Join(notes, " · "))
+}
+
+func (a *App) footer() string {
+	switch a.mode {
+	case modeInput:
+		return a.prompt + string(a.input) + "▏"
+	case modeConfirm:
+		return a.prompt
+	}
+	return a.message
+}
+
+// fit cuts s to at most width runes, marking the cut with an ellipsis.
+func fit(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width <= 0 {
+		return ""
+	}
+	return string(r[:width-1]) + "…"
+}
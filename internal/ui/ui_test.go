@@ -0,0 +1,199 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jingikim/ccq/internal/queue"
+	"github.com/jingikim/ccq/internal/status"
+	"github.com/jingikim/ccq/internal/tmux"
+)
+
+// setup returns an app over a fake session with three windows: @0 active and
+// busy, @1 and @2 idle.
+func setup(t *testing.T) (*App, *tmux.Fake, []string) {
+	t.Helper()
+	f := tmux.NewFake("ccq-fake-ui")
+	windows, _ := f.ListWindows()
+	w0 := windows[0].ID
+	w1, _ := f.NewWindow("/src/api")
+	w2, _ := f.NewWindow("/src/web")
+	f.SelectWindow(w0)
+
+	q := queue.New(f)
+	q.MarkBusy(w0)
+	q.MarkIdle(w1)
+	q.MarkIdle(w2)
+	f.SetWindowOption(w1, status.GitKey, fmt.Sprintf("%d 0 0 1 main /src/api", time.Now().Unix()))
+
+	a := New(f)
+	return a, f, []string{w0, w1, w2}
+}
+
+func press(a *App, keys ...Key) (quit bool) {
+	for _, k := range keys {
+		if a.HandleKey(k) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("j\x1b[A\x1b[B\r\x7fé\x03\x1b\x1b[1;5C\x01q"))
+	want := []Key{"j", KeyUp, KeyDown, KeyEnter, KeyBackspace, "é", KeyCtrlC, KeyEsc, "q"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeys = %q, want %q", got, want)
+	}
+}
+
+func TestNavigateAndJump(t *testing.T) {
+	a, f, ids := setup(t)
+	if a.Selected() != ids[0] {
+		t.Fatalf("expected the active window selected initially, got %s", a.Selected())
+	}
+
+	press(a, "j", "j", "j")
+	if a.Selected() != ids[2] {
+		t.Errorf("expected selection to stop at the last window, got %s", a.Selected())
+	}
+	press(a, KeyUp)
+	if a.Selected() != ids[1] {
+		t.Errorf("expected up to select %s, got %s", ids[1], a.Selected())
+	}
+
+	if !press(a, KeyEnter) {
+		t.Error("expected enter to quit after jumping")
+	}
+	if active, _ := f.ActiveWindowID(); active != ids[1] {
+		t.Errorf("expected %s selected in tmux, got %s", ids[1], active)
+	}
+}
+
+func TestSnoozePrompt(t *testing.T) {
+	a, f, ids := setup(t)
+	q := queue.New(f)
+
+	// Prefilled with the default; replace it with 1h
+	press(a, "j", "s", KeyBackspace, KeyBackspace, KeyBackspace, "1", "h", KeyEnter)
+	until := q.SnoozedUntil(ids[1])
+	if d := time.Until(time.Unix(until, 0)); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expected %s snoozed for 1h, got %v", ids[1], d)
+	}
+	if shell := f.Shell(); !slices.Contains(shell, "sleep 3600; ccq _reconcile --retry") {
+		t.Errorf("expected a switch retry scheduled for when the snooze runs out, got %q", shell)
+	}
+
+	press(a, "s", KeyBackspace, KeyBackspace, KeyBackspace, "o", "f", "f", KeyEnter)
+	if q.SnoozedUntil(ids[1]) != 0 {
+		t.Error("expected off to clear the snooze")
+	}
+
+	press(a, "s", KeyBackspace, KeyBackspace, KeyBackspace, "x", KeyEnter)
+	if !strings.Contains(a.View(120, 20, time.Now()), "invalid duration") {
+		t.Error("expected an invalid duration to be reported")
+	}
+
+	press(a, "s", KeyEsc)
+	if a.mode != modeList || q.SnoozedUntil(ids[1]) != 0 {
+		t.Error("expected esc to cancel the prompt")
+	}
+}
+
+func TestPriorityAndExclude(t *testing.T) {
+	a, f, ids := setup(t)
+	q := queue.New(f)
+
+	press(a, "j", "p")
+	if got := q.Priority(ids[1]); got != queue.PriorityHigh {
+		t.Errorf("expected high after one press, got %s", got)
+	}
+	press(a, "p", "p")
+	if got := q.Priority(ids[1]); got != queue.PriorityNormal {
+		t.Errorf("expected priority to cycle back to normal, got %s", got)
+	}
+
+	press(a, "x")
+	if !q.IsExcluded(ids[1]) {
+		t.Error("expected x to exclude the window")
+	}
+	press(a, "x")
+	if q.IsExcluded(ids[1]) {
+		t.Error("expected a second x to include it again")
+	}
+}
+
+func TestKillConfirm(t *testing.T) {
+	a, f, ids := setup(t)
+
+	press(a, "j", "j", "D", "n")
+	if windows, _ := f.ListWindows(); len(windows) != 3 {
+		t.Fatalf("expected no kill without confirmation, got %d windows", len(windows))
+	}
+
+	press(a, "D", "y")
+	windows, _ := f.ListWindows()
+	if len(windows) != 2 {
+		t.Fatalf("expected window killed, got %d windows", len(windows))
+	}
+	for _, w := range windows {
+		if w.ID == ids[2] {
+			t.Errorf("expected %s to be killed", ids[2])
+		}
+	}
+	if a.Selected() == ids[2] {
+		t.Error("expected the selection to move off the killed window")
+	}
+}
+
+func TestSendPrompt(t *testing.T) {
+	a, f, ids := setup(t)
+
+	press(a, "j", "i", "h", "i", KeyEnter)
+	want := [][]string{
+		{"send-keys", "-t", ids[1], "-l", "hi"},
+		{"send-keys", "-t", ids[1], "Enter"},
+	}
+	if got := f.SentKeys(ids[1]); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected prompt typed literally, then Enter, got %q", got)
+	}
+
+	// An empty prompt sends nothing
+	press(a, "i", KeyEnter)
+	if got := f.SentKeys(ids[1]); len(got) != 2 {
+		t.Errorf("expected nothing more sent, got %q", got)
+	}
+}
+
+func TestView(t *testing.T) {
+	a, _, _ := setup(t)
+	press(a, "j")
+
+	out := a.View(120, 12, time.Now())
+	lines := strings.Split(out, "\r\n")
+	if len(lines) != 12 {
+		t.Fatalf("expected 12 lines, got %d", len(lines))
+	}
+	if !strings.Contains(out, "→○ #1 ") {
+		t.Errorf("expected #1 marked as next target:\n%s", out)
+	}
+	if !strings.Contains(out, reverseOn+"→○ #1 ") {
+		t.Errorf("expected selected row in reverse video:\n%s", out)
+	}
+	if !strings.Contains(out, "main*") || !strings.Contains(out, "api") {
+		t.Errorf("expected git status and directory columns:\n%s", out)
+	}
+	if !strings.Contains(lines[len(lines)-1], "q quit") {
+		t.Errorf("expected help on the last line, got %q", lines[len(lines)-1])
+	}
+
+	// Rows that do not fit are dropped, keeping the footer
+	small := strings.Split(a.View(120, 4, time.Now()), "\r\n")
+	if len(small) != 4 || !strings.Contains(small[3], "q quit") {
+		t.Errorf("expected 4 lines ending in help, got %q", small)
+	}
+}
@@ -16,14 +16,55 @@ FIFO queue-based auto-switcher for multiple Claude Code sessions via tmux.
 
 Usage:
   ccq             Start ccq or add a new Claude window
+  ccq -- <args>   Same, passing args to the command (e.g. -- --model opus)
+  ccq new [dir] [--name N] [--profile name] [--worktree branch] [--background] [-- args]
+                  Add a window in dir (default: current), optionally named,
+                  with a launch profile, in a git worktree of dir's repo
+                  checked out at branch, or without switching to it
+  ccq spawn <tasks.json>
+                  Open the windows listed in a task file in the background,
+                  submitting each one's prompt once Claude Code is ready
+  ccq done [window] [--remove [--force]]
+                  Close a window, and with --remove its git worktree
+                  (refused if it has uncommitted changes, unless --force)
   ccq attach      Attach to existing session (no new window)
-  ccq status      Show session status
+  ccq status [--json | --format <template> | --watch [--interval 2s]]
+                  Show session status (JSON, a Go text/template, or live)
+  ccq next        Go to the window auto-switch would serve next (also in
+                  manual mode)
+  ccq prev        Go back to the window left with ccq next
+  ccq back        Undo the latest window switch made by ccq (repeat to walk
+                  further back)
+  ccq ui          Full-screen dashboard: jump, snooze, prioritize, exclude,
+                  kill or prompt windows
+  ccq priority [window] high|normal|low
+                  Set a window's queue priority
+  ccq snooze [window] <duration|off>
+                  Skip a window in the queue for a while (e.g. 20m)
+  ccq exclude [window]
+                  Never auto-switch to (or away from) a window
+  ccq include [window]
+                  Undo ccq exclude
+  ccq policy [name]
+                  Show or set the scheduling policy
+                  (fifo, lifo, round-robin, weighted)
+  ccq daemon      Serve hooks and status from memory over a Unix socket
+                  (optional; runs until the session ends)
+  ccq serve [--listen host:port|unix:/path]
+                  Serve the local HTTP/JSON API (default 127.0.0.1:7767)
   ccq -h, --help  Show this help
   ccq --version   Show version
 
 Keybindings (inside ccq session):
   prefix + a      Toggle auto/manual switching
   prefix + g      Toggle dashboard (gauge)
+  prefix + P      Set current window priority
+  prefix + S      Snooze current window
+  prefix + X      Exclude/include current window
+  prefix + N/B    ccq next / ccq prev
+  prefix + BSpace ccq back
+  prefix + O      Open a window in a recent or entered directory
+  prefix + u      Open the full-screen dashboard in a popup
   prefix + n/p    Next/previous window
   prefix + w      Window list
   prefix + d      Detach from session
@@ -34,11 +75,13 @@ func main() {
 	var err error
 
 	if len(os.Args) < 2 {
-		err = cmd.Root()
+		err = cmd.Root(nil)
 	} else {
 		switch os.Args[1] {
 		case "-h", "--help", "help":
 			printHelp()
+		case "--":
+			err = cmd.Root(os.Args[2:])
 		case "--version", "-v":
 			fmt.Printf("ccq version %s\n", Version)
 			return
@@ -48,12 +91,46 @@ func main() {
 				os.Exit(1)
 			}
 			err = cmd.Hook(os.Args[2])
+		case "_reconcile":
+			err = cmd.Reconcile(os.Args[2:])
+		case "_git":
+			err = cmd.RefreshGit()
+		case "_new_menu":
+			err = cmd.NewMenu(os.Args[2:])
 		case "_toggle":
 			err = cmd.Toggle()
 		case "_status":
 			err = cmd.Status()
 		case "status":
-			err = cmd.SessionStatus()
+			err = cmd.SessionStatus(os.Args[2:])
+		case "next":
+			err = cmd.Next(os.Args[2:])
+		case "prev":
+			err = cmd.Prev(os.Args[2:])
+		case "back":
+			err = cmd.Back(os.Args[2:])
+		case "ui":
+			err = cmd.UI()
+		case "priority":
+			err = cmd.Priority(os.Args[2:])
+		case "snooze":
+			err = cmd.Snooze(os.Args[2:])
+		case "exclude":
+			err = cmd.Exclude(os.Args[2:])
+		case "include":
+			err = cmd.Include(os.Args[2:])
+		case "policy":
+			err = cmd.Policy(os.Args[2:])
+		case "daemon":
+			err = cmd.Daemon()
+		case "serve":
+			err = cmd.Serve(os.Args[2:])
+		case "new":
+			err = cmd.New(os.Args[2:])
+		case "spawn":
+			err = cmd.Spawn(os.Args[2:])
+		case "done":
+			err = cmd.Done(os.Args[2:])
 		case "attach":
 			err = cmd.Attach()
 		case "toggle-dashboard":